@@ -9,7 +9,9 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/teslausb-go/teslausb/internal/archive"
 	"github.com/teslausb-go/teslausb/internal/config"
+	"github.com/teslausb-go/teslausb/internal/disk"
 	"github.com/teslausb-go/teslausb/internal/monitor"
 	"github.com/teslausb-go/teslausb/internal/state"
 	"github.com/teslausb-go/teslausb/internal/system"
@@ -49,7 +51,10 @@ func main() {
 		config.Save(*configPath, &config.Config{
 			Temperature: config.Temperature{WarningCelsius: 70, CautionCelsius: 60},
 		})
-		config.Load(*configPath)
+		cfg, _ = config.Load(*configPath)
+	}
+	if err := archive.Validate(cfg); err != nil {
+		log.Printf("archive config: %v", err)
 	}
 
 	// Apply system tuning
@@ -71,6 +76,9 @@ func main() {
 	go monitor.RunTemperatureMonitor(ctx)
 	go monitor.RunWiFiMonitor(ctx)
 
+	// Snapshots only live as long as the process that took them
+	disk.CleanSnapshots()
+
 	// Create state machine
 	machine := state.New()
 
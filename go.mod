@@ -3,6 +3,12 @@ module github.com/teslausb-go/teslausb
 go 1.25.0
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/pkg/sftp v1.13.10
+	golang.org/x/crypto v0.48.0
 	golang.org/x/net v0.51.0
+	golang.org/x/sys v0.41.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/kr/fs v0.1.0 // indirect
@@ -2,30 +2,36 @@ package archive
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/teslausb-go/teslausb/internal/config"
-	"github.com/teslausb-go/teslausb/internal/disk"
 )
 
 const ArchiveMount = "/mnt/archive"
 
-// IsReachable checks if the archive server is reachable via TCP.
-func IsReachable() bool {
-	cfg := config.Get()
-	if cfg == nil {
-		return false
-	}
-	if cfg.Archive.Method == "cifs" {
-		return tcpReachable(cfg.CIFS.Server, "445")
+var (
+	activeMu sync.Mutex
+	active   Target
+)
+
+// IsReachable reports the highest-priority archive target that is
+// currently reachable, if any.
+func IsReachable() (string, bool) {
+	targets, err := Targets(config.Get())
+	if err != nil {
+		return "", false
 	}
-	return tcpReachable(cfg.NFS.Server, "2049")
+	t, ok := firstReachable(targets)
+	return t.Name, ok
 }
 
 func tcpReachable(host, port string) bool {
@@ -40,150 +46,187 @@ func tcpReachable(host, port string) bool {
 	return true
 }
 
-// MountArchive mounts the archive share based on configured method.
-func MountArchive() error {
-	cfg := config.Get()
-	if cfg != nil && cfg.Archive.Method == "cifs" {
-		return MountCIFS()
+// MountArchive prepares the highest-priority reachable archive target for
+// ArchiveClips, falling over to the next reachable target if one can't be
+// prepared. Returns the name of the target in use.
+func MountArchive(ctx context.Context) (string, error) {
+	targets, err := Targets(config.Get())
+	if err != nil {
+		return "", err
+	}
+	var errs []error
+	for _, t := range targets {
+		if !t.Reachable() {
+			continue
+		}
+		if err := t.Prepare(ctx); err != nil {
+			log.Printf("archive target %s: %v", t.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+			continue
+		}
+		activeMu.Lock()
+		active = t
+		activeMu.Unlock()
+		return t.Name, nil
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("no archive target reachable")
 	}
-	return MountNFS()
+	return "", errors.Join(errs...)
 }
 
-// UnmountArchive unmounts the archive share.
+// UnmountArchive tears down the destination prepared by MountArchive.
 func UnmountArchive() {
+	activeMu.Lock()
+	a := active
+	active = Target{}
+	activeMu.Unlock()
+	if a.Archiver != nil {
+		a.Teardown()
+		return
+	}
+	// Nothing prepared in this process; clear any stale mount anyway
+	unmountArchive()
+}
+
+func unmountArchive() {
 	exec.Command("umount", "-f", "-l", ArchiveMount).Run()
 	log.Println("archive unmounted")
 }
 
-const cifsCredFile = "/mutable/teslausb/.cifs-credentials"
+// mountedShare implements Transfer and Teardown for backends that mount
+// the destination at ArchiveMount.
+type mountedShare struct{}
 
-// MountCIFS mounts the configured CIFS/SMB share with auto-negotiation.
-// Uses a credentials file so passwords aren't visible in ps output.
-func MountCIFS() error {
+func (mountedShare) Transfer(ctx context.Context, clips []Clip, t *Tracker) (Result, error) {
 	cfg := config.Get()
-	if cfg == nil {
-		return fmt.Errorf("no config")
+	if cfg != nil && cfg.Archive.Engine == EngineNative {
+		var opts copyOptions
+		if cfg.Archive.Verify != "" {
+			newHash, err := newHasher(cfg.Archive.Verify)
+			if err != nil {
+				var res Result
+				res.skipAll(clips)
+				return res, err
+			}
+			opts.newHash = newHash
+		}
+		return copyClips(ctx, ArchiveMount, clips, t, opts)
 	}
-	os.MkdirAll(ArchiveMount, 0755)
-	source := fmt.Sprintf("//%s/%s", cfg.CIFS.Server, cfg.CIFS.Share)
-
-	// Write credentials to a file (mode 0600) to keep passwords out of ps output
-	credContent := fmt.Sprintf("username=%s\npassword=%s\n", cfg.CIFS.Username, cfg.CIFS.Password)
-	if err := os.WriteFile(cifsCredFile, []byte(credContent), 0600); err != nil {
-		return fmt.Errorf("write CIFS credentials: %w", err)
+	if cfg != nil && cfg.Archive.Verify != "" {
+		return rsyncVerified(ctx, ArchiveMount, clips, t, cfg.Archive.Verify)
 	}
-	defer os.Remove(cifsCredFile)
+	return rsyncClips(ctx, ArchiveMount, clips, t, rsyncOptions{keepSource: t.keepsSources()})
+}
+
+func (mountedShare) Teardown() {
+	unmountArchive()
+}
 
-	opts := fmt.Sprintf("credentials=%s,iocharset=utf8,file_mode=0777,dir_mode=0777", cifsCredFile)
+func (mountedShare) ListStored(ctx context.Context) ([]StoredFile, error) {
+	return listLocal(ArchiveMount)
+}
 
-	// Try SMB versions in order: 3.0, 2.1, 2.0
-	for _, ver := range []string{"3.0", "2.1", "2.0"} {
-		verOpts := opts + ",vers=" + ver
-		if err := exec.Command("mount", "-t", "cifs", source, ArchiveMount, "-o", verOpts).Run(); err == nil {
-			log.Printf("CIFS mounted: %s (SMB %s)", source, ver)
+func (mountedShare) RemoveStored(ctx context.Context, dir string, files []string) error {
+	return removeLocal(ArchiveMount, dir, files)
+}
+
+func listLocal(root string) ([]StoredFile, error) {
+	var files []StoredFile
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
 			return nil
 		}
-	}
-	return fmt.Errorf("mount CIFS %s: all SMB versions failed", source)
+		rel, _ := filepath.Rel(root, p)
+		files = append(files, StoredFile{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return files, err
 }
 
-// MountNFS mounts the configured NFS share.
-func MountNFS() error {
-	cfg := config.Get()
-	if cfg == nil {
-		return fmt.Errorf("no config")
+func removeLocal(root, dir string, files []string) error {
+	for _, f := range files {
+		if err := os.Remove(filepath.Join(root, filepath.FromSlash(f))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
-	os.MkdirAll(ArchiveMount, 0755)
-	source := fmt.Sprintf("%s:%s", cfg.NFS.Server, cfg.NFS.Share)
-	opts := "rw,noauto,nolock,proto=tcp,vers=3"
-	if err := exec.Command("mount", "-t", "nfs", source, ArchiveMount, "-o", opts).Run(); err != nil {
-		return fmt.Errorf("mount NFS %s: %w", source, err)
+	// Remove fails on the first directory that still has entries
+	for _, d := range emptiedDirs(dir, files) {
+		if os.Remove(filepath.Join(root, filepath.FromSlash(d))) != nil {
+			break
+		}
 	}
-	log.Printf("NFS mounted: %s", source)
 	return nil
 }
 
-// ArchiveClips copies SavedClips and SentryClips (and optionally RecentClips) to the NFS share via rsync.
-// Returns clip count and bytes transferred.
-func ArchiveClips(ctx context.Context) (int, int64, error) {
-	clipDirs := []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"}
-	if cfg := config.Get(); cfg != nil && cfg.Archive.RecentClips {
-		clipDirs = append(clipDirs, "TeslaCam/RecentClips")
+// ArchiveClips copies SavedClips and SentryClips (and optionally RecentClips)
+// to the destination prepared by MountArchive, passing progress snapshots to
+// report (which may be nil) and recording each clip in j (which may be nil).
+// The run holds between files while g (which may be nil) is paused, and
+// cancelling ctx stops it once the files in flight are finished. The rsync
+// engine copies in batches of up to rsyncBatchFiles files of one event, and
+// holds or stops between batches. The Result counts what this run archived.
+func ArchiveClips(ctx context.Context, src Source, report func(Progress), j *Journal, g *Gate) (Result, error) {
+	activeMu.Lock()
+	a := active
+	activeMu.Unlock()
+	if a.Archiver == nil {
+		return Result{}, fmt.Errorf("archive destination not mounted")
 	}
-	totalClips := 0
-	totalBytes := int64(0)
-
-	for _, dir := range clipDirs {
-		src := filepath.Join(disk.MountPoint, dir)
-		if _, err := os.Stat(src); os.IsNotExist(err) {
-			continue
-		}
-
-		// Check if there are any files to archive
-		entries, _ := os.ReadDir(src)
-		if len(entries) == 0 {
-			continue
-		}
-
-		dst := filepath.Join(ArchiveMount, dir) + "/"
-		os.MkdirAll(dst, 0755)
 
-		log.Printf("archiving %s (%d items)", dir, len(entries))
-
-		// Build rsync command — use direct src/dst (no -R with absolute paths)
-		args := []string{
-			"-avhL",
-			"--no-o", "--no-g", // NFS root-squash workaround
-			"--remove-source-files",
-			"--no-perms",
-			"--omit-dir-times",
-			src + "/",
-			dst,
+	cfg := config.Get()
+	sel := selectClips(src.Root, cfg, time.Now())
+	if len(sel.clips) == 0 {
+		log.Println("no clips to archive")
+		return Result{Target: a.Name, Backend: a.Archiver.Name(), Destination: a.Describe()}, nil
+	}
+	drop := removeClip
+	if src.Snapshot {
+		// The live image drops them with the archived clips
+		drop = func(c Clip) error {
+			j.dropped(c)
+			return nil
 		}
-
-		cmd := exec.CommandContext(ctx, "rsync", args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		errCh := make(chan error, 1)
-		go func() {
-			errCh <- cmd.Run()
-		}()
-
-		select {
-		case err := <-errCh:
-			if err != nil {
-				// Exit code 24 = partial transfer (acceptable)
-				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 24 {
-					log.Println("rsync: partial transfer (some files vanished)")
-				} else {
-					return totalClips, totalBytes, fmt.Errorf("rsync %s: %w", dir, err)
-				}
-			}
-		case <-ctx.Done():
-			cmd.Process.Kill()
-			return totalClips, totalBytes, ctx.Err()
+	}
+	clips, filtered := applyFilters(sel.clips, sel.decisions, drop)
+	prioritize(clips)
+
+	var res Result
+	var err error
+	if len(clips) > 0 {
+		log.Printf("archiving %d clips to %s (%s %s)", len(clips), a.Name, a.Archiver.Name(), a.Describe())
+		if cfg != nil {
+			setBandwidth(cfg.Archive.BandwidthLimitKB)
 		}
-
-		// Count archived items and bytes on destination
-		dstEntries, _ := os.ReadDir(dst)
-		for _, e := range dstEntries {
-			if e.IsDir() {
-				continue
-			}
-			totalClips++
-			if info, err := e.Info(); err == nil {
-				totalBytes += info.Size()
-			}
+		tracker := NewTracker(clips, report)
+		tracker.journal = j
+		tracker.gate = g
+		tracker.snapshot = src.Snapshot
+		if cfg != nil {
+			tracker.workers = cfg.Archive.Workers
 		}
+		res, err = a.Transfer(ctx, clips, tracker)
+		tracker.Close()
 	}
+	res.Target, res.Backend, res.Destination = a.Name, a.Archiver.Name(), a.Describe()
+	res.Snapshot = src.Snapshot
+	res.Filtered = filtered
+	res.annotate(sel.events)
+	log.Printf("archived %d clips (%d events, %d bytes), %d skipped, %d failed",
+		res.Clips, res.Events, res.Bytes, res.Skipped, res.Failed)
 
 	// Clean empty directories in source
-	for _, dir := range clipDirs {
-		cleanEmptyDirs(filepath.Join(disk.MountPoint, dir))
+	if !src.Snapshot {
+		for _, dir := range sel.clipDirs {
+			cleanEmptyDirs(filepath.Join(src.Root, dir))
+		}
 	}
 
-	return totalClips, totalBytes, nil
+	return res, err
 }
 
 func cleanEmptyDirs(root string) {
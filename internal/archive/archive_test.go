@@ -1,12 +1,98 @@
 package archive
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/teslausb-go/teslausb/internal/config"
 )
 
 func TestIsReachableNoConfig(t *testing.T) {
 	// With no config loaded, should return false
-	if IsReachable() {
+	if _, ok := IsReachable(); ok {
 		t.Error("expected false with no config")
 	}
 }
+
+func TestMethodsRegistered(t *testing.T) {
+	methods := Methods()
+	for _, want := range []string{"cifs", "nfs"} {
+		found := false
+		for _, m := range methods {
+			if m == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s backend to be registered, got %v", want, methods)
+		}
+	}
+}
+
+func TestNewDefaultsToNFS(t *testing.T) {
+	a, err := New(&config.Config{NFS: config.NFS{Server: "10.0.0.1", Share: "/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name() != "nfs" {
+		t.Errorf("expected nfs, got %s", a.Name())
+	}
+	if a.Describe() != "10.0.0.1:/data" {
+		t.Errorf("expected 10.0.0.1:/data, got %s", a.Describe())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{"unconfigured nfs", config.Config{}, false},
+		{"unknown method", config.Config{Archive: config.Archive{Method: "ftp"}}, true},
+		{"nfs missing share", config.Config{NFS: config.NFS{Server: "nas"}}, true},
+		{"nfs relative share", config.Config{NFS: config.NFS{Server: "nas", Share: "volume1"}}, true},
+		{"cifs ok", config.Config{Archive: config.Archive{Method: "cifs"}, CIFS: config.CIFS{Server: "nas", Share: "TeslaCam"}}, false},
+		{"cifs missing share", config.Config{Archive: config.Archive{Method: "cifs"}, CIFS: config.CIFS{Server: "nas"}}, true},
+		{"verify xxhash", config.Config{Archive: config.Archive{Verify: "xxhash"}}, false},
+		{"verify unknown", config.Config{Archive: config.Archive{Verify: "md5"}}, true},
+		{"engine native", config.Config{Archive: config.Archive{Engine: "native"}}, false},
+		{"engine unknown", config.Config{Archive: config.Archive{Engine: "robocopy"}}, true},
+		{"window ok", config.Config{Archive: config.Archive{Windows: []config.ArchiveWindow{{Days: []string{"Mon", "tuesday"}, Start: "22:00", End: "06:00"}}}}, false},
+		{"window bad day", config.Config{Archive: config.Archive{Windows: []config.ArchiveWindow{{Days: []string{"xyz"}, Start: "22:00", End: "06:00"}}}}, true},
+		{"window bad time", config.Config{Archive: config.Archive{Windows: []config.ArchiveWindow{{Start: "10pm", End: "06:00"}}}}, true},
+		{"emergency too high", config.Config{Archive: config.Archive{EmergencyFreePercent: 60}}, true},
+		{"negative bandwidth", config.Config{Archive: config.Archive{BandwidthLimitKB: -1}}, true},
+		{"workers ok", config.Config{Archive: config.Archive{Workers: 4}}, false},
+		{"too many workers", config.Config{Archive: config.Archive{Workers: 32}}, true},
+		{"wake ok", config.Config{Archive: config.Archive{WakeOnLAN: config.WakeOnLAN{MAC: "00:11:22:33:44:55", Broadcast: "192.168.1.255"}}, Home: config.Home{SSIDs: []string{"HomeWiFi"}}}, false},
+		{"wake bad mac", config.Config{Archive: config.Archive{WakeOnLAN: config.WakeOnLAN{MAC: "nas"}}, Home: config.Home{SSIDs: []string{"HomeWiFi"}}}, true},
+		{"wake bad broadcast", config.Config{Archive: config.Archive{WakeOnLAN: config.WakeOnLAN{MAC: "00:11:22:33:44:55", Broadcast: "lan"}}, Home: config.Home{Gateways: []string{"192.168.1.1"}}}, true},
+		{"negative leave checks", config.Config{Home: config.Home{LeaveChecks: -1}}, true},
+		{"drives ok", config.Config{Drives: config.Drives{MusicGB: 8, BoomboxGB: 1}}, false},
+		{"negative drive size", config.Config{Drives: config.Drives{LightShowGB: -1}}, true},
+		{"wake without home", config.Config{Archive: config.Archive{WakeOnLAN: config.WakeOnLAN{MAC: "00:11:22:33:44:55"}}}, true},
+		{"targets ok", config.Config{Targets: []config.Target{
+			{Name: "home", Method: "nfs", NFS: config.NFS{Server: "nas", Share: "/data"}},
+			{Name: "cabin", Method: "cifs", CIFS: config.CIFS{Server: "nas2", Share: "TeslaCam"}},
+		}}, false},
+		{"retention ok", config.Config{Archive: config.Archive{Retention: []config.RetentionRule{{Category: "SentryClips", MaxAgeDays: 30}}}}, false},
+		{"retention unknown category", config.Config{Archive: config.Archive{Retention: []config.RetentionRule{{Category: "Sentry"}}}}, true},
+		{"retention without category in template", config.Config{Archive: config.Archive{PathTemplate: "{year}/{event}", Retention: []config.RetentionRule{{Category: "SavedClips", MaxSizeGB: 100}}}}, true},
+		{"filter ok", config.Config{Archive: config.Archive{Filters: []config.FilterRule{{Cameras: []string{"*_repeater"}, Action: "delete"}}}}, false},
+		{"filter bad action", config.Config{Archive: config.Archive{Filters: []config.FilterRule{{Action: "drop"}}}}, true},
+		{"filter bad pattern", config.Config{Archive: config.Archive{Filters: []config.FilterRule{{Reasons: []string{"["}, Action: "skip"}}}}, true},
+		{"target missing name", config.Config{Targets: []config.Target{{Method: "nfs"}}}, true},
+		{"target bad settings", config.Config{Targets: []config.Target{{Name: "home", Method: "nfs", NFS: config.NFS{Server: "nas"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+var errTest = errors.New("test error")
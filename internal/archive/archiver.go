@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// DefaultMethod is used when config.Archive.Method is empty.
+const DefaultMethod = "nfs"
+
+// Archiver is an archive destination. Backends register a Factory under a
+// method name and are selected by config.Archive.Method.
+type Archiver interface {
+	// Name returns the method the backend is registered under.
+	Name() string
+	// Describe returns a human-readable destination for logs and notifications.
+	Describe() string
+	// Reachable reports whether the destination can currently be reached.
+	Reachable() bool
+	// Prepare readies the destination for a transfer (e.g. mounts a share).
+	Prepare(ctx context.Context) error
+	// Transfer archives clips from the cam disk, removing each source file
+	// once it is safely stored, and reports per-file progress to t.
+	// The Result accounts for every clip, even when an error is returned.
+	Transfer(ctx context.Context, clips []Clip, t *Tracker) (Result, error)
+	// Teardown releases anything Prepare set up. Safe to call more than once.
+	Teardown()
+}
+
+// Factory builds an Archiver from config. It returns an error when the
+// backend's settings are invalid, which doubles as config validation.
+type Factory func(cfg *config.Config) (Archiver, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Factory{}
+)
+
+// Register makes a backend available under the given method name.
+// Backends call it from init.
+func Register(method string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, dup := backends[method]; dup {
+		panic("archive: backend registered twice: " + method)
+	}
+	backends[method] = factory
+}
+
+// Methods returns the registered method names, sorted.
+func Methods() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	methods := make([]string, 0, len(backends))
+	for m := range backends {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// New builds the archiver selected by cfg.Archive.Method.
+func New(cfg *config.Config) (Archiver, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no config")
+	}
+	method := cfg.Archive.Method
+	if method == "" {
+		method = DefaultMethod
+	}
+	backendsMu.RLock()
+	factory, ok := backends[method]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown archive method %q (available: %s)", method, strings.Join(Methods(), ", "))
+	}
+	return factory(cfg)
+}
+
+// Validate checks the archive settings of cfg against the backend of each
+// archive target.
+func Validate(cfg *config.Config) error {
+	if _, err := Targets(cfg); err != nil {
+		return err
+	}
+	if err := ValidateTemplate(cfg.Archive.PathTemplate); err != nil {
+		return err
+	}
+	if err := ValidateRetention(cfg.Archive.Retention, cfg.Archive.PathTemplate); err != nil {
+		return err
+	}
+	if err := ValidateFilters(cfg.Archive.Filters); err != nil {
+		return err
+	}
+	if _, err := parseWindows(cfg.Archive.Windows); err != nil {
+		return err
+	}
+	if p := cfg.Archive.EmergencyFreePercent; p < 0 || p > 50 {
+		return fmt.Errorf("emergency_free_percent must be between 0 and 50")
+	}
+	if w := cfg.Archive.Workers; w < 0 || w > MaxWorkers {
+		return fmt.Errorf("workers must be between 0 and %d", MaxWorkers)
+	}
+	if cfg.Home.ArriveChecks < 0 || cfg.Home.LeaveChecks < 0 {
+		return fmt.Errorf("home.arrive_checks and home.leave_checks must not be negative")
+	}
+	if d := cfg.Drives; d.MusicGB < 0 || d.LightShowGB < 0 || d.BoomboxGB < 0 {
+		return fmt.Errorf("drive sizes must not be negative")
+	}
+	if err := ValidateWakeOnLAN(cfg.Archive.WakeOnLAN, cfg.Home); err != nil {
+		return err
+	}
+	if cfg.Archive.BandwidthLimitKB < 0 {
+		return fmt.Errorf("bandwidth_limit_kb must not be negative")
+	}
+	switch cfg.Archive.Engine {
+	case "", EngineRsync, EngineNative:
+	default:
+		return fmt.Errorf("unknown archive engine %q (available: %s, %s)", cfg.Archive.Engine, EngineRsync, EngineNative)
+	}
+	if cfg.Archive.Verify != "" {
+		if _, err := newHasher(cfg.Archive.Verify); err != nil {
+			return err
+		}
+	}
+	return nil
+}
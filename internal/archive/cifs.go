@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func init() {
+	Register("cifs", newCIFS)
+}
+
+const cifsCredFile = "/mutable/teslausb/.cifs-credentials"
+
+type cifsArchiver struct {
+	mountedShare
+	server   string
+	share    string
+	username string
+	password string
+}
+
+func newCIFS(cfg *config.Config) (Archiver, error) {
+	a := &cifsArchiver{
+		server:   cfg.CIFS.Server,
+		share:    cfg.CIFS.Share,
+		username: cfg.CIFS.Username,
+		password: cfg.CIFS.Password,
+	}
+	if a.server != "" && a.share == "" {
+		return nil, fmt.Errorf("cifs: share is required")
+	}
+	return a, nil
+}
+
+func (a *cifsArchiver) Name() string { return "cifs" }
+
+func (a *cifsArchiver) Describe() string {
+	return fmt.Sprintf("//%s/%s", a.server, a.share)
+}
+
+func (a *cifsArchiver) Reachable() bool {
+	return tcpReachable(a.server, "445")
+}
+
+// Prepare mounts the configured CIFS/SMB share with auto-negotiation.
+// Uses a credentials file so passwords aren't visible in ps output.
+func (a *cifsArchiver) Prepare(ctx context.Context) error {
+	os.MkdirAll(ArchiveMount, 0755)
+	source := a.Describe()
+
+	// Write credentials to a file (mode 0600) to keep passwords out of ps output
+	credContent := fmt.Sprintf("username=%s\npassword=%s\n", a.username, a.password)
+	if err := os.WriteFile(cifsCredFile, []byte(credContent), 0600); err != nil {
+		return fmt.Errorf("write CIFS credentials: %w", err)
+	}
+	defer os.Remove(cifsCredFile)
+
+	opts := fmt.Sprintf("credentials=%s,iocharset=utf8,file_mode=0777,dir_mode=0777", cifsCredFile)
+
+	// Try SMB versions in order: 3.0, 2.1, 2.0
+	for _, ver := range []string{"3.0", "2.1", "2.0"} {
+		verOpts := opts + ",vers=" + ver
+		if err := exec.CommandContext(ctx, "mount", "-t", "cifs", source, ArchiveMount, "-o", verOpts).Run(); err == nil {
+			log.Printf("CIFS mounted: %s (SMB %s)", source, ver)
+			return nil
+		}
+	}
+	return fmt.Errorf("mount CIFS %s: all SMB versions failed", source)
+}
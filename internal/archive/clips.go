@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Clip is a single file on the cam disk queued for archiving.
+type Clip struct {
+	Dir     string // cam disk directory, e.g. "TeslaCam/SentryClips"
+	RelPath string // path below Dir, slash-separated
+	Path    string // absolute source path
+	Dest    string // destination path relative to the archive root, slash-separated
+	Size    int64
+	ModTime time.Time
+	Keep    bool // leave on the cam disk once archived
+}
+
+// Key returns the clip's slash-separated path relative to the cam disk root.
+func (c Clip) Key() string {
+	return path.Join(c.Dir, c.RelPath)
+}
+
+// collectClips lists every regular file under root/dir for each dir.
+func collectClips(root string, clipDirs []string) []Clip {
+	var clips []Clip
+	for _, dir := range clipDirs {
+		base := filepath.Join(root, dir)
+		filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := os.Stat(p) // follow symlinks like rsync -L
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, _ := filepath.Rel(base, p)
+			c := Clip{
+				Dir:     dir,
+				RelPath: filepath.ToSlash(rel),
+				Path:    p,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+			c.Dest = c.Key() // same layout as the cam disk until a template is applied
+			clips = append(clips, c)
+			return nil
+		})
+	}
+	return clips
+}
+
+// putFunc stores one clip at the destination, reporting bytes sent to t, and
+// returns nil only once the stored copy has been confirmed intact.
+type putFunc func(ctx context.Context, c Clip, t *Tracker) error
+
+// transferEach stores clips one at a time with put and removes each source
+// file only after put succeeds, mirroring rsync --remove-source-files.
+// Clips are handed out in order to the run's workers. Failed clips are left
+// on the cam disk for the next run; a copy that fails verification is sent
+// again up to verifyAttempts times first. Cancelling ctx stops the run
+// before the next clip; clips in flight are finished.
+func transferEach(ctx context.Context, clips []Clip, t *Tracker, put putFunc) (Result, error) {
+	var (
+		mu       sync.Mutex
+		res      Result
+		firstErr error
+	)
+	runPool(len(clips), t.poolSize(), func(i int) {
+		c := clips[i]
+		if err := t.Wait(ctx); err != nil {
+			mu.Lock()
+			res.add(c, skipped)
+			mu.Unlock()
+			return
+		}
+		t.Start(c)
+		err := put(context.WithoutCancel(ctx), c, t)
+		// Finish settles the bytes a resend reports a second time
+		for attempt := 2; attempt <= verifyAttempts && errors.Is(err, errChecksumMismatch); attempt++ {
+			log.Printf("verify: resending %s (attempt %d of %d)", c.Key(), attempt, verifyAttempts)
+			err = put(context.WithoutCancel(ctx), c, t)
+		}
+		t.Finish(c, err)
+		if err != nil {
+			log.Printf("archive %s: %v", c.Key(), err)
+			mu.Lock()
+			res.add(c, failed)
+			res.FailedFiles = append(res.FailedFiles, FileError{Path: c.Key(), Error: err.Error()})
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			return
+		}
+		t.Confirm(c)
+		t.removeSource(c)
+		mu.Lock()
+		res.add(c, archived)
+		mu.Unlock()
+	})
+
+	if err := ctx.Err(); err != nil && res.Skipped > 0 {
+		return res, err
+	}
+	if res.Failed > 0 {
+		return res, fmt.Errorf("%d of %d clips failed: %w", res.Failed, len(clips), firstErr)
+	}
+	return res, nil
+}
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Copy engines for backends that mount the destination.
+const (
+	EngineRsync  = "rsync"
+	EngineNative = "native"
+)
+
+// copyPartialExt marks a clip the native engine is still writing. A run cut
+// short leaves it behind, and the next run resumes from its size.
+const copyPartialExt = ".partial"
+
+// copyOptions adjusts how copyClips copies.
+type copyOptions struct {
+	newHash func() hash.Hash // when set, each copy is read back and compared
+}
+
+// copyClips copies clips into dstRoot without rsync. Each clip is written to
+// a partial file, synced, given the source's mtime and renamed into place;
+// its source is removed only once that has succeeded.
+func copyClips(ctx context.Context, dstRoot string, clips []Clip, t *Tracker, opts copyOptions) (Result, error) {
+	return transferEach(ctx, clips, t, func(ctx context.Context, c Clip, t *Tracker) error {
+		return copyFile(ctx, c, filepath.Join(dstRoot, filepath.FromSlash(c.Dest)), t, opts)
+	})
+}
+
+// copyFile copies one clip to dst, resuming a partial copy left by an
+// earlier attempt.
+func copyFile(ctx context.Context, c Clip, dst string, t *Tracker, opts copyOptions) error {
+	// A previous run may have renamed the clip but lost power before
+	// removing the source
+	if info, err := os.Stat(dst); err == nil && info.Size() == c.Size {
+		return verifyNative(ctx, c, dst, opts)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(dst), err)
+	}
+
+	src, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	partial := dst + copyPartialExt
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", partial, err)
+	}
+	defer f.Close()
+	offset := int64(0)
+	if info, err := f.Stat(); err == nil && info.Size() <= c.Size {
+		offset = info.Size()
+	} else if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("truncate %s: %w", partial, err)
+	}
+	if offset > 0 {
+		log.Printf("copy: resuming %s at %d bytes", c.Key(), offset)
+		t.Add(c, offset)
+	}
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, t.Reader(c, throttle(ctx, src))); err != nil {
+		return fmt.Errorf("write %s: %w", partial, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", partial, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", partial, err)
+	}
+
+	info, err := os.Stat(partial)
+	if err != nil {
+		return err
+	}
+	if info.Size() != c.Size {
+		return fmt.Errorf("copy %s: size %d, expected %d", partial, info.Size(), c.Size)
+	}
+	if err := os.Chtimes(partial, c.ModTime, c.ModTime); err != nil {
+		return fmt.Errorf("set mtime %s: %w", partial, err)
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		return fmt.Errorf("rename %s: %w", partial, err)
+	}
+	syncDir(filepath.Dir(dst))
+	return verifyNative(ctx, c, dst, opts)
+}
+
+// verifyNative compares a finished copy with its source when verification
+// is on. A bad copy is removed so the next run starts over.
+func verifyNative(ctx context.Context, c Clip, dst string, opts copyOptions) error {
+	if opts.newHash == nil {
+		return nil
+	}
+	if err := verifyCopy(ctx, c, dst, opts.newHash); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("verify %s: %w", c.Key(), err)
+	}
+	return nil
+}
+
+// syncDir makes a rename durable. Network filesystems may not support
+// syncing a directory, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a SavedClips or SentryClips event folder, described by the
+// event.json Tesla writes alongside the clips.
+type Event struct {
+	Dir       string    `json:"dir"`      // e.g. "TeslaCam/SentryClips/2024-05-01_18-22-10"
+	Category  string    `json:"category"` // e.g. "SentryClips"
+	Timestamp time.Time `json:"timestamp"`
+	City      string    `json:"city,omitempty"`
+	Lat       float64   `json:"lat,omitempty"`
+	Lon       float64   `json:"lon,omitempty"`
+	Reason    string    `json:"reason,omitempty"` // e.g. "sentry_aware_object_detection"
+	Camera    string    `json:"camera,omitempty"` // index of the camera that triggered the event
+	HasThumb  bool      `json:"has_thumb"`
+}
+
+// eventFolderLayout is how Tesla names event folders.
+const eventFolderLayout = "2006-01-02_15-04-05"
+
+// eventJSON mirrors event.json, where every value is a string:
+//
+//	{"timestamp":"2024-05-01T18:22:10","city":"San Francisco","est_lat":"37.7749",
+//	 "est_lon":"-122.4194","reason":"sentry_aware_object_detection","camera":"0"}
+type eventJSON struct {
+	Timestamp string    `json:"timestamp"`
+	City      string    `json:"city"`
+	EstLat    flexFloat `json:"est_lat"`
+	EstLon    flexFloat `json:"est_lon"`
+	Reason    string    `json:"reason"`
+	Camera    string    `json:"camera"`
+}
+
+// flexFloat accepts a number or a numeric string.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// ParseEvent reads the event folder at root/dir, where dir is relative to
+// the cam disk root. The timestamp falls back to the folder name when
+// event.json is missing it; a missing event.json is an error.
+func ParseEvent(root, dir string) (Event, error) {
+	ev := Event{
+		Dir:      dir,
+		Category: path.Base(path.Dir(dir)),
+	}
+	abs := filepath.Join(root, filepath.FromSlash(dir))
+	data, err := os.ReadFile(filepath.Join(abs, "event.json"))
+	if err != nil {
+		return ev, err
+	}
+	var raw eventJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return ev, fmt.Errorf("parse %s/event.json: %w", dir, err)
+	}
+	ev.City = raw.City
+	ev.Lat = float64(raw.EstLat)
+	ev.Lon = float64(raw.EstLon)
+	ev.Reason = raw.Reason
+	ev.Camera = raw.Camera
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", raw.Timestamp, time.Local); err == nil {
+		ev.Timestamp = t
+	} else if t, err := time.ParseInLocation(eventFolderLayout, path.Base(dir), time.Local); err == nil {
+		ev.Timestamp = t
+	}
+	if _, err := os.Stat(filepath.Join(abs, "thumb.png")); err == nil {
+		ev.HasThumb = true
+	}
+	return ev, nil
+}
+
+// parseEvents parses the event.json of every event folder among clips,
+// keyed by event folder. It must run before the clips are transferred.
+func parseEvents(root string, clips []Clip) map[string]Event {
+	events := map[string]Event{}
+	for _, c := range clips {
+		dir := c.event()
+		if dir == "" || path.Base(c.RelPath) != "event.json" {
+			continue
+		}
+		if ev, err := ParseEvent(root, dir); err == nil {
+			events[dir] = ev
+		}
+	}
+	return events
+}
+
+// annotate attaches parsed event details to the events r archived and
+// counts them by reason.
+func (r *Result) annotate(events map[string]Event) {
+	dirs := make([]string, 0, len(r.events))
+	for dir := range r.events {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		ev, ok := events[dir]
+		if !ok {
+			continue
+		}
+		r.ArchivedEvents = append(r.ArchivedEvents, ev)
+		if ev.Reason == "" {
+			continue
+		}
+		delta := Tally{Reasons: map[string]int{ev.Reason: 1}}
+		cat := r.Categories[ev.Category]
+		cat.Add(delta)
+		r.Categories[ev.Category] = cat
+		r.Tally.Add(delta)
+	}
+}
+
+// Summary describes the archived events per category, e.g.
+// "3 sentry events (2 × sentry_aware_object_detection, 1 × user_interaction_honk) in San Francisco".
+// It returns "" when no events were archived.
+func (r Result) Summary() string {
+	var parts []string
+	for _, cat := range []string{"SentryClips", "SavedClips"} {
+		t := r.Categories[cat]
+		if t.Events == 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(cat, "Clips"))
+		s := fmt.Sprintf("%d %s event", t.Events, name)
+		if t.Events != 1 {
+			s += "s"
+		}
+		if reasons := formatReasons(t.Reasons); reasons != "" {
+			s += " (" + reasons + ")"
+		}
+		var cities []string
+		seen := map[string]bool{}
+		for _, ev := range r.ArchivedEvents {
+			if ev.Category == cat && ev.City != "" && !seen[ev.City] {
+				seen[ev.City] = true
+				cities = append(cities, ev.City)
+			}
+		}
+		if len(cities) > 0 {
+			s += " in " + strings.Join(cities, ", ")
+		}
+		parts = append(parts, s)
+	}
+	counts := map[string]int{}
+	for _, o := range r.Filtered {
+		counts[o.Action] += o.Clips
+	}
+	var filtered []string
+	if n := counts[FilterDelete]; n > 0 {
+		filtered = append(filtered, fmt.Sprintf("%d deleted", n))
+	}
+	if n := counts[FilterSkip]; n > 0 {
+		filtered = append(filtered, fmt.Sprintf("%d left on the cam disk", n))
+	}
+	if n := counts[FilterArchive]; n > 0 {
+		filtered = append(filtered, fmt.Sprintf("%d archived and kept on the cam disk", n))
+	}
+	if len(filtered) > 0 {
+		parts = append(parts, "filtered clips: "+strings.Join(filtered, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatReasons lists reasons by descending count, e.g. "2 × a, 1 × b".
+func formatReasons(reasons map[string]int) string {
+	names := make([]string, 0, len(reasons))
+	for name := range reasons {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if reasons[names[i]] != reasons[names[j]] {
+			return reasons[names[i]] > reasons[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%d × %s", reasons[name], name)
+	}
+	return strings.Join(names, ", ")
+}
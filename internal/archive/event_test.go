@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeEvent(t *testing.T, root, dir, eventJSON string) {
+	t.Helper()
+	p := filepath.Join(root, dir, "event.json")
+	os.MkdirAll(filepath.Dir(p), 0755)
+	if err := os.WriteFile(p, []byte(eventJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	root := t.TempDir()
+	dir := "TeslaCam/SentryClips/2024-05-01_18-22-10"
+	writeEvent(t, root, dir, `{"timestamp":"2024-05-01T18:22:10","city":"San Francisco","est_lat":"37.7749","est_lon":"-122.4194","reason":"sentry_aware_object_detection","camera":"5"}`)
+	writeClip(t, root, dir+"/thumb.png", 10)
+
+	ev, err := ParseEvent(root, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Category != "SentryClips" || ev.City != "San Francisco" || ev.Reason != "sentry_aware_object_detection" || ev.Camera != "5" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev.Lat != 37.7749 || ev.Lon != -122.4194 {
+		t.Errorf("unexpected location %v,%v", ev.Lat, ev.Lon)
+	}
+	if want := time.Date(2024, 5, 1, 18, 22, 10, 0, time.Local); !ev.Timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, ev.Timestamp)
+	}
+	if !ev.HasThumb {
+		t.Error("expected thumb.png to be detected")
+	}
+
+	// Older firmware omits fields; the folder name still gives the time
+	writeEvent(t, root, "TeslaCam/SavedClips/2023-01-02_03-04-05", `{"reason":"user_interaction_honk","est_lat":37.5}`)
+	ev, err = ParseEvent(root, "TeslaCam/SavedClips/2023-01-02_03-04-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Lat != 37.5 || ev.Timestamp.Year() != 2023 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	if _, err := ParseEvent(root, "TeslaCam/SavedClips/missing"); err == nil {
+		t.Error("expected error for folder without event.json")
+	}
+}
+
+func TestResultSummary(t *testing.T) {
+	root := t.TempDir()
+	writeEvent(t, root, "TeslaCam/SentryClips/a", `{"city":"Oakland","reason":"sentry_aware_object_detection"}`)
+	writeEvent(t, root, "TeslaCam/SentryClips/b", `{"city":"Oakland","reason":"sentry_aware_object_detection"}`)
+	writeEvent(t, root, "TeslaCam/SentryClips/c", `{"city":"Berkeley","reason":"user_interaction_honk"}`)
+	writeEvent(t, root, "TeslaCam/SavedClips/d", `{"reason":"user_interaction_dashcam_icon_tapped"}`)
+	clips := collectClips(root, []string{"TeslaCam/SentryClips", "TeslaCam/SavedClips"})
+	events := parseEvents(root, clips)
+
+	var res Result
+	for _, c := range clips {
+		res.add(c, archived)
+	}
+	res.annotate(events)
+
+	want := "3 sentry events (2 × sentry_aware_object_detection, 1 × user_interaction_honk) in Oakland, Berkeley; " +
+		"1 saved event (1 × user_interaction_dashcam_icon_tapped)"
+	if got := res.Summary(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if len(res.ArchivedEvents) != 4 || res.Reasons["sentry_aware_object_detection"] != 2 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
@@ -0,0 +1,206 @@
+package archive
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// Filter actions.
+const (
+	FilterArchive       = "archive"        // archive, keeping the clip on the cam disk
+	FilterArchiveDelete = "archive_delete" // archive, then remove from the cam disk
+	FilterSkip          = "skip"           // leave on the cam disk
+	FilterDelete        = "delete"         // remove from the cam disk without archiving
+)
+
+// archives reports whether action archives the clip.
+func archives(action string) bool {
+	return action == FilterArchive || action == FilterArchiveDelete
+}
+
+// FilterOutcome counts the clips one filter rule decided in a run.
+type FilterOutcome struct {
+	Rule   int    `json:"rule"` // 1-based position in archive.filters
+	Action string `json:"action"`
+	Clips  int    `json:"clips"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// ValidateFilters checks each rule has a known action, category and
+// well-formed patterns.
+func ValidateFilters(rules []config.FilterRule) error {
+	for i, r := range rules {
+		switch r.Action {
+		case FilterArchive, FilterArchiveDelete, FilterSkip, FilterDelete:
+		default:
+			return fmt.Errorf("filter %d: action must be archive, archive_delete, skip or delete, got %q", i+1, r.Action)
+		}
+		for _, cat := range r.Categories {
+			if !slices.Contains(clipCategories, cat) {
+				return fmt.Errorf("filter %d: unknown category %q", i+1, cat)
+			}
+		}
+		for _, p := range append(slices.Clone(r.Cameras), r.Reasons...) {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("filter %d: bad pattern %q", i+1, p)
+			}
+		}
+		if r.MinAgeDays < 0 || r.MaxAgeDays < 0 {
+			return fmt.Errorf("filter %d: ages must not be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// Camera returns the camera a clip was recorded by, taken from its name
+// (e.g. "2024-05-01_18-22-10-left_repeater.mp4"), or "" for files such as
+// event.json and thumb.png.
+func (c Clip) Camera() string {
+	name := path.Base(c.RelPath)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	n := len(eventFolderLayout)
+	if len(name) <= n+1 || name[n] != '-' {
+		return ""
+	}
+	if _, err := time.Parse(eventFolderLayout, name[:n]); err != nil {
+		return ""
+	}
+	return name[n+1:]
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRule returns the index of the first rule matching c, or -1.
+func matchRule(rules []config.FilterRule, c Clip, ev Event, now time.Time) int {
+	ts := c.ModTime
+	if !ev.Timestamp.IsZero() {
+		ts = ev.Timestamp
+	}
+	age := now.Sub(ts)
+	const day = 24 * time.Hour
+	for i, r := range rules {
+		if len(r.Categories) > 0 && !slices.Contains(r.Categories, c.Category()) {
+			continue
+		}
+		if !matchAny(r.Cameras, c.Camera()) || !matchAny(r.Reasons, ev.Reason) {
+			continue
+		}
+		if r.MinAgeDays > 0 && age < time.Duration(r.MinAgeDays)*day {
+			continue
+		}
+		if r.MaxAgeDays > 0 && age > time.Duration(r.MaxAgeDays)*day {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// filterDecision is what the filter rules decided for one clip.
+type filterDecision struct {
+	rule   int // -1 when no rule matched
+	action string
+}
+
+// decideFilters decides each clip by the first matching rule; unmatched
+// clips are archived and removed. Files without a camera, such as
+// event.json, follow the videos of their event: kept on the cam disk with
+// any video that is, otherwise archived if any video is, otherwise skipped
+// if any is.
+func decideFilters(clips []Clip, events map[string]Event, rules []config.FilterRule, now time.Time) []filterDecision {
+	decide := func(c Clip) filterDecision {
+		d := filterDecision{rule: matchRule(rules, c, events[c.event()], now), action: FilterArchiveDelete}
+		if d.rule >= 0 {
+			d.action = rules[d.rule].Action
+		}
+		return d
+	}
+	rank := map[string]int{FilterDelete: 0, FilterSkip: 1, FilterArchiveDelete: 2, FilterArchive: 3}
+	decisions := make([]filterDecision, len(clips))
+	byEvent := map[string]filterDecision{} // strongest decision among an event's videos
+	for i, c := range clips {
+		if c.Camera() == "" && c.event() != "" {
+			continue
+		}
+		d := decide(c)
+		decisions[i] = d
+		if ev := c.event(); ev != "" {
+			if prev, ok := byEvent[ev]; !ok || rank[d.action] > rank[prev.action] {
+				byEvent[ev] = d
+			}
+		}
+	}
+	for i, c := range clips {
+		if c.Camera() != "" || c.event() == "" {
+			continue
+		}
+		d, ok := byEvent[c.event()]
+		if !ok {
+			// An event without videos is decided on its own
+			d = decide(c)
+		}
+		decisions[i] = d
+	}
+	return decisions
+}
+
+// applyFilters carries out decisions: clips to archive are returned, those
+// to keep on the cam disk marked Keep, skipped clips stay on the cam disk
+// and deleted ones are passed to drop.
+func applyFilters(clips []Clip, decisions []filterDecision, drop func(Clip) error) ([]Clip, []FilterOutcome) {
+	var keep []Clip
+	tally := map[filterDecision]*FilterOutcome{}
+	for i, c := range clips {
+		d := decisions[i]
+		if d.action == FilterDelete {
+			if err := drop(c); err != nil {
+				log.Printf("filter: delete %s: %v", c.Key(), err)
+				continue
+			}
+		}
+		if archives(d.action) {
+			c.Keep = d.action == FilterArchive
+			keep = append(keep, c)
+		}
+		if d.rule < 0 {
+			continue
+		}
+		o := tally[d]
+		if o == nil {
+			o = &FilterOutcome{Rule: d.rule + 1, Action: d.action}
+			tally[d] = o
+		}
+		o.Clips++
+		o.Bytes += c.Size
+	}
+	if len(tally) == 0 {
+		return keep, nil
+	}
+
+	outcomes := make([]FilterOutcome, 0, len(tally))
+	for _, o := range tally {
+		outcomes = append(outcomes, *o)
+	}
+	sort.Slice(outcomes, func(i, j int) bool { return outcomes[i].Rule < outcomes[j].Rule })
+	for _, o := range outcomes {
+		log.Printf("filter %d: %s %d clips (%d bytes)", o.Rule, o.Action, o.Clips, o.Bytes)
+	}
+	return keep, outcomes
+}
@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func TestClipCamera(t *testing.T) {
+	tests := map[string]string{
+		"2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4":         "front",
+		"2024-05-01_18-22-10/2024-05-01_18-12-10-left_repeater.mp4": "left_repeater",
+		"2024-05-01_18-22-10/event.json":                            "",
+		"2024-05-01_18-22-10/thumb.png":                             "",
+		"2024-05-01_18-12-10-back.mp4":                              "back",
+		"notes-front.mp4":                                           "",
+	}
+	for rel, want := range tests {
+		if got := (Clip{RelPath: rel}).Camera(); got != want {
+			t.Errorf("Camera(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestApplyFiltersArchiveKeeps(t *testing.T) {
+	root := t.TempDir()
+	writeEvent(t, root, "TeslaCam/SavedClips/2024-05-03_09-00-00", `{"reason":"user_interaction_honk"}`)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-03_09-00-00/2024-05-03_08-50-00-front.mp4", 100)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-03_09-00-00/2024-05-03_08-50-00-back.mp4", 100)
+	writeClip(t, root, "TeslaCam/SentryClips/2024-05-01_18-12-10-front.mp4", 100)
+
+	clips := collectClips(root, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"})
+	events := parseEvents(root, clips)
+	rules := []config.FilterRule{
+		{Categories: []string{"SavedClips"}, Cameras: []string{"front"}, Action: FilterArchive},
+	}
+	keep, _ := applyFilters(clips, decideFilters(clips, events, rules, time.Now()), removeClip)
+	if len(keep) != 4 {
+		t.Fatalf("expected all 4 clips archived, got %d", len(keep))
+	}
+	for _, c := range keep {
+		// event.json stays with the kept front clip; unmatched clips are removed once archived
+		want := c.Category() == "SavedClips" && c.Camera() != "back"
+		if c.Keep != want {
+			t.Errorf("%s: Keep = %v, want %v", c.Key(), c.Keep, want)
+		}
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	root := t.TempDir()
+	// Object detection: front and repeaters archived, back and pillars deleted
+	writeEvent(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10", `{"reason":"sentry_aware_object_detection"}`)
+	for _, cam := range []string{"front", "left_repeater", "back", "left_pillar"} {
+		writeClip(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-"+cam+".mp4", 100)
+	}
+	// Bumped into: everything deleted, event.json included
+	writeEvent(t, root, "TeslaCam/SentryClips/2024-05-02_09-00-00", `{"reason":"sentry_aware_accel_0.3"}`)
+	writeClip(t, root, "TeslaCam/SentryClips/2024-05-02_09-00-00/2024-05-02_08-50-00-front.mp4", 100)
+	// Saved clips don't match the sentry rules
+	writeEvent(t, root, "TeslaCam/SavedClips/2024-05-03_09-00-00", `{"reason":"user_interaction_honk"}`)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-03_09-00-00/2024-05-03_08-50-00-back.mp4", 100)
+	// Old RecentClips stay on the cam disk
+	writeClip(t, root, "TeslaCam/RecentClips/2024-04-01_10-00-00-front.mp4", 100)
+
+	clips := collectClips(root, []string{"TeslaCam/SentryClips", "TeslaCam/SavedClips", "TeslaCam/RecentClips"})
+	old := time.Now().AddDate(0, 0, -10)
+	for i := range clips {
+		if clips[i].Category() == "RecentClips" {
+			clips[i].ModTime = old
+		}
+	}
+	events := parseEvents(root, clips)
+	rules := []config.FilterRule{
+		{Categories: []string{"SentryClips"}, Reasons: []string{"sentry_aware_object_detection"}, Cameras: []string{"front", "*_repeater"}, Action: FilterArchiveDelete},
+		{Categories: []string{"SentryClips"}, Action: FilterDelete},
+		{Categories: []string{"RecentClips"}, MinAgeDays: 7, Action: FilterSkip},
+	}
+	keep, outcomes := applyFilters(clips, decideFilters(clips, events, rules, time.Now()), removeClip)
+
+	kept := map[string]bool{}
+	for _, c := range keep {
+		kept[c.Key()] = true
+	}
+	for _, key := range []string{
+		"TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4",
+		"TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-left_repeater.mp4",
+		"TeslaCam/SentryClips/2024-05-01_18-22-10/event.json",
+		"TeslaCam/SavedClips/2024-05-03_09-00-00/2024-05-03_08-50-00-back.mp4",
+		"TeslaCam/SavedClips/2024-05-03_09-00-00/event.json",
+	} {
+		if !kept[key] {
+			t.Errorf("expected %s to be archived", key)
+		}
+	}
+	if len(keep) != 5 {
+		t.Errorf("expected 5 clips to archive, got %d", len(keep))
+	}
+
+	for _, c := range clips {
+		_, err := os.Stat(c.Path)
+		deleted := os.IsNotExist(err)
+		wantDeleted := c.event() == "TeslaCam/SentryClips/2024-05-02_09-00-00" ||
+			c.Camera() == "back" && c.Category() == "SentryClips" || c.Camera() == "left_pillar"
+		if deleted != wantDeleted {
+			t.Errorf("%s: deleted = %v, want %v", c.Key(), deleted, wantDeleted)
+		}
+	}
+
+	want := []FilterOutcome{
+		{Rule: 1, Action: FilterArchiveDelete, Clips: 3, Bytes: 200 + int64(len(`{"reason":"sentry_aware_object_detection"}`))},
+		{Rule: 2, Action: FilterDelete, Clips: 4, Bytes: 300 + int64(len(`{"reason":"sentry_aware_accel_0.3"}`))},
+		{Rule: 3, Action: FilterSkip, Clips: 1, Bytes: 100},
+	}
+	if len(outcomes) != len(want) {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+	for i := range want {
+		if outcomes[i] != want[i] {
+			t.Errorf("outcome %d = %+v, want %+v", i, outcomes[i], want[i])
+		}
+	}
+
+	res := Result{Filtered: outcomes}
+	if got := res.Summary(); got != "filtered clips: 4 deleted, 1 left on the cam disk" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
@@ -3,8 +3,10 @@ package archive
 import (
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,17 +16,22 @@ import (
 
 const minReserveBytes = 2 * 1024 * 1024 * 1024 // 2GB minimum reserve
 
-func reserveBytes() int64 {
-	pct := 10
-	if cfg := config.Get(); cfg != nil && cfg.Archive.ReservePercent > 0 {
-		pct = cfg.Archive.ReservePercent
+// diskSpace returns the free and total bytes of the filesystem holding
+// root. It is replaced in tests.
+var diskSpace = func(root string) (free, total int64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(root, &stat); err != nil {
+		return 0, 0, err
 	}
+	return int64(stat.Bavail) * int64(stat.Bsize), int64(stat.Blocks) * int64(stat.Bsize), nil
+}
 
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(disk.MountPoint, &stat); err != nil {
-		return minReserveBytes
+// reserveBytes returns the free space to keep on a cam disk of total bytes.
+func reserveBytes(cfg *config.Config, total int64) int64 {
+	pct := 10
+	if cfg != nil && cfg.Archive.ReservePercent > 0 {
+		pct = cfg.Archive.ReservePercent
 	}
-	total := int64(stat.Blocks) * int64(stat.Bsize)
 	reserve := total * int64(pct) / 100
 	if reserve < minReserveBytes {
 		return minReserveBytes
@@ -33,49 +40,167 @@ func reserveBytes() int64 {
 }
 
 type fileEntry struct {
-	path    string
+	key     string // slash path relative to the cam disk root
 	modTime time.Time
 	size    int64
 }
 
-func ManageFreeSpace() {
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(disk.MountPoint, &stat); err != nil {
-		return
+// FreedClip is a cam disk file ManageFreeSpace deleted.
+type FreedClip struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"` // "recent_clips" or "emergency"
+}
+
+// FreeSpaceReport describes a ManageFreeSpace pass that had to free space.
+type FreeSpaceReport struct {
+	Needed    int64       `json:"needed"`
+	Freed     int64       `json:"freed"`
+	Emergency bool        `json:"emergency"` // free space was below archive.emergency_free_percent
+	Clips     []FreedClip `json:"clips"`
+}
+
+// isProtected reports whether key is, or is inside, a protected event.
+func isProtected(key string, protected []string) bool {
+	for _, p := range protected {
+		if key == p || strings.HasPrefix(key, p+"/") {
+			return true
+		}
 	}
-	free := int64(stat.Bavail) * int64(stat.Bsize)
-	reserve := reserveBytes()
+	return false
+}
 
-	if free >= reserve {
-		return
+// planFreeSpace picks files to delete to free needed bytes. The oldest
+// RecentClips go first, unless archiveRecent says they are archived too.
+// Other clips still on the cam disk haven't been archived, so they are
+// only picked in an emergency, whole events at a time, oldest first.
+// Protected events are never picked.
+func planFreeSpace(files []fileEntry, needed int64, emergency, archiveRecent bool, protected []string) []FreedClip {
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	expendable := func(f fileEntry) bool {
+		return !archiveRecent && strings.HasPrefix(f.key, "TeslaCam/RecentClips/")
+	}
+
+	var plan []FreedClip
+	freed := int64(0)
+	for _, f := range files {
+		if freed >= needed {
+			return plan
+		}
+		if !expendable(f) || isProtected(f.key, protected) {
+			continue
+		}
+		plan = append(plan, FreedClip{Path: f.key, Size: f.size, Reason: "recent_clips"})
+		freed += f.size
+	}
+	if !emergency {
+		return plan
+	}
+
+	// Group the rest by event folder, in order of each event's oldest file
+	groups := map[string][]fileEntry{}
+	var order []string
+	for _, f := range files {
+		if expendable(f) || isProtected(f.key, protected) {
+			continue
+		}
+		group := f.key
+		if dir, rest, ok := strings.Cut(f.key, "/"); ok {
+			if cat, rest, ok := strings.Cut(rest, "/"); ok {
+				if event, _, ok := strings.Cut(rest, "/"); ok {
+					group = path.Join(dir, cat, event)
+				}
+			}
+		}
+		if _, ok := groups[group]; !ok {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], f)
+	}
+	for _, group := range order {
+		if freed >= needed {
+			break
+		}
+		for _, f := range groups[group] {
+			plan = append(plan, FreedClip{Path: f.key, Size: f.size, Reason: "emergency"})
+			freed += f.size
+		}
 	}
+	return plan
+}
 
-	needed := reserve - free
-	log.Printf("free space: %d MB, need %d MB more", free/(1024*1024), needed/(1024*1024))
+// freeSpaceClipDirs are the cam disk folders ManageFreeSpace deletes from.
+var freeSpaceClipDirs = []string{"TeslaCam/RecentClips", "TeslaCam/SavedClips", "TeslaCam/SentryClips"}
 
-	// Collect all clips sorted by modification time (oldest first)
+// camFiles lists the files in the clip folders of the cam disk at root.
+func camFiles(root string) []fileEntry {
 	var files []fileEntry
-	for _, dir := range []string{"TeslaCam/RecentClips", "TeslaCam/SavedClips", "TeslaCam/SentryClips"} {
-		filepath.Walk(filepath.Join(disk.MountPoint, dir), func(path string, info os.FileInfo, err error) error {
+	for _, dir := range freeSpaceClipDirs {
+		filepath.Walk(filepath.Join(root, dir), func(p string, info os.FileInfo, err error) error {
 			if err != nil || info.IsDir() {
 				return nil
 			}
-			files = append(files, fileEntry{path: path, modTime: info.ModTime(), size: info.Size()})
+			rel, _ := filepath.Rel(root, p)
+			files = append(files, fileEntry{key: filepath.ToSlash(rel), modTime: info.ModTime(), size: info.Size()})
 			return nil
 		})
 	}
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].modTime.Before(files[j].modTime)
-	})
+	return files
+}
 
-	freed := int64(0)
-	for _, f := range files {
-		if freed >= needed {
-			break
+// freeSpaceNeed returns a report of how much ManageFreeSpace must free on
+// a cam disk with free of total bytes available under cfg, or nil if the
+// reserve is already free.
+func freeSpaceNeed(cfg *config.Config, free, total int64) *FreeSpaceReport {
+	reserve := reserveBytes(cfg, total)
+	if free >= reserve {
+		return nil
+	}
+	report := &FreeSpaceReport{Needed: reserve - free, Clips: []FreedClip{}}
+	if cfg != nil && cfg.Archive.EmergencyFreePercent > 0 {
+		report.Emergency = free < total*int64(cfg.Archive.EmergencyFreePercent)/100
+	}
+	return report
+}
+
+// ManageFreeSpace deletes clips from the cam disk until the configured
+// reserve is free, following planFreeSpace. Returns nil if nothing had to
+// be freed.
+func ManageFreeSpace() *FreeSpaceReport {
+	free, total, err := diskSpace(disk.MountPoint)
+	if err != nil {
+		return nil
+	}
+	cfg := config.Get()
+	report := freeSpaceNeed(cfg, free, total)
+	if report == nil {
+		return nil
+	}
+	archiveRecent := cfg != nil && cfg.Archive.RecentClips
+	log.Printf("free space: %d MB, need %d MB more (emergency: %v)",
+		free/(1024*1024), report.Needed/(1024*1024), report.Emergency)
+
+	protected, err := ProtectedEvents()
+	if err != nil {
+		log.Printf("protected events: %v", err)
+	}
+
+	for _, c := range planFreeSpace(camFiles(disk.MountPoint), report.Needed, report.Emergency, archiveRecent, protected) {
+		if err := os.Remove(filepath.Join(disk.MountPoint, filepath.FromSlash(c.Path))); err != nil {
+			log.Printf("free space: %v", err)
+			continue
 		}
-		os.Remove(f.path)
-		freed += f.size
-		log.Printf("freed: %s (%d KB)", filepath.Base(f.path), f.size/1024)
+		report.Clips = append(report.Clips, c)
+		report.Freed += c.Size
+		log.Printf("freed: %s (%d KB, %s)", c.Path, c.Size/1024, c.Reason)
+	}
+	for _, dir := range freeSpaceClipDirs[1:] {
+		cleanEmptyDirs(filepath.Join(disk.MountPoint, dir))
+	}
+	log.Printf("freed %d MB total", report.Freed/(1024*1024))
+	if report.Freed < report.Needed {
+		log.Printf("free space: %d MB short of the reserve; remaining clips are unarchived or protected",
+			(report.Needed-report.Freed)/(1024*1024))
 	}
-	log.Printf("freed %d MB total", freed/(1024*1024))
+	return report
 }
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlanFreeSpace(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	files := []fileEntry{
+		{key: "TeslaCam/SavedClips/2024-04-01_10-00-00/front.mp4", modTime: at(0), size: 100},
+		{key: "TeslaCam/SavedClips/2024-04-01_10-00-00/back.mp4", modTime: at(5), size: 100},
+		{key: "TeslaCam/SentryClips/2024-04-02_10-00-00/front.mp4", modTime: at(1), size: 100},
+		{key: "TeslaCam/SentryClips/2024-04-03_10-00-00/front.mp4", modTime: at(2), size: 100},
+		{key: "TeslaCam/RecentClips/2024-04-04_10-00-00-front.mp4", modTime: at(4), size: 50},
+		{key: "TeslaCam/RecentClips/2024-04-04_09-00-00-front.mp4", modTime: at(3), size: 50},
+	}
+	protected := []string{"TeslaCam/SavedClips/2024-04-01_10-00-00"}
+	paths := func(plan []FreedClip) []string {
+		var out []string
+		for _, c := range plan {
+			out = append(out, filepath.Base(filepath.Dir(c.Path))+"/"+filepath.Base(c.Path)+":"+c.Reason)
+		}
+		return out
+	}
+
+	// Without an emergency only RecentClips go, even if that isn't enough
+	got := paths(planFreeSpace(slices.Clone(files), 1000, false, false, protected))
+	want := []string{"RecentClips/2024-04-04_09-00-00-front.mp4:recent_clips", "RecentClips/2024-04-04_10-00-00-front.mp4:recent_clips"}
+	if !slices.Equal(got, want) {
+		t.Errorf("no emergency: got %v, want %v", got, want)
+	}
+
+	// Stop as soon as enough is freed
+	got = paths(planFreeSpace(slices.Clone(files), 40, true, false, protected))
+	if len(got) != 1 {
+		t.Errorf("expected one RecentClip to be enough, got %v", got)
+	}
+
+	// In an emergency unarchived events go oldest first, protected ones never
+	got = paths(planFreeSpace(slices.Clone(files), 150, true, false, protected))
+	want = append(want, "2024-04-02_10-00-00/front.mp4:emergency")
+	if !slices.Equal(got, want) {
+		t.Errorf("emergency: got %v, want %v", got, want)
+	}
+	got = paths(planFreeSpace(slices.Clone(files), 1000, true, false, protected))
+	if len(got) != 4 {
+		t.Errorf("expected everything but the protected event, got %v", got)
+	}
+
+	// With archive.recent_clips, RecentClips are unarchived like the rest
+	if got = paths(planFreeSpace(slices.Clone(files), 1000, false, true, protected)); len(got) != 0 {
+		t.Errorf("no emergency, archiving RecentClips: expected nothing, got %v", got)
+	}
+	got = paths(planFreeSpace(slices.Clone(files), 150, true, true, protected))
+	want = []string{"2024-04-02_10-00-00/front.mp4:emergency", "2024-04-03_10-00-00/front.mp4:emergency"}
+	if !slices.Equal(got, want) {
+		t.Errorf("emergency, archiving RecentClips: got %v, want %v", got, want)
+	}
+}
+
+func TestSetProtected(t *testing.T) {
+	old := protectFile
+	protectFile = filepath.Join(t.TempDir(), "protected.json")
+	defer func() { protectFile = old }()
+
+	SetProtected("TeslaCam/SentryClips/b", true)
+	SetProtected("/TeslaCam/SavedClips/a/", true)
+	SetProtected("TeslaCam/SentryClips/b", true)
+	events, err := ProtectedEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"TeslaCam/SavedClips/a", "TeslaCam/SentryClips/b"}; !slices.Equal(events, want) {
+		t.Errorf("got %v, want %v", events, want)
+	}
+	SetProtected("TeslaCam/SavedClips/a", false)
+	if events, _ := ProtectedEvents(); !slices.Equal(events, []string{"TeslaCam/SentryClips/b"}) {
+		t.Errorf("after unprotect got %v", events)
+	}
+}
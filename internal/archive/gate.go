@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"context"
+	"sync"
+)
+
+// Gate pauses an archive run between files. The zero value is open; a nil
+// *Gate never pauses.
+type Gate struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{} // closed on Resume
+}
+
+// Pause holds the run before its next file. It returns false if the gate
+// was already paused.
+func (g *Gate) Pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.resume = make(chan struct{})
+	return true
+}
+
+// Resume lets a paused run continue. It returns false if the gate wasn't
+// paused.
+func (g *Gate) Resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	close(g.resume)
+	return true
+}
+
+// Paused reports whether the gate is holding the run.
+func (g *Gate) Paused() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// wait blocks while the gate is paused, returning ctx's error if the run is
+// cancelled meanwhile.
+func (g *Gate) wait(ctx context.Context) error {
+	if g == nil {
+		return ctx.Err()
+	}
+	g.mu.Lock()
+	paused, resume := g.paused, g.resume
+	g.mu.Unlock()
+	if !paused {
+		return ctx.Err()
+	}
+	select {
+	case <-resume:
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
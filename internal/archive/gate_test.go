@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTransferEachPauseAndCancel(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeClip(t, root, "TeslaCam/SavedClips/2024-05-01_18-22-10/"+name+".mp4", 10)
+	}
+	clips := collectClips(root, []string{"TeslaCam/SavedClips"})
+
+	g := &Gate{}
+	g.Pause()
+	tracker := NewTracker(clips, nil)
+	tracker.gate = g
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan context.Context)
+	release := make(chan struct{})
+	put := func(ctx context.Context, c Clip, t *Tracker) error {
+		started <- ctx
+		<-release
+		return ctx.Err()
+	}
+	type outcome struct {
+		res Result
+		err error
+	}
+	done := make(chan outcome)
+	go func() {
+		res, err := transferEach(ctx, clips, tracker, put)
+		done <- outcome{res, err}
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("clip started while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !tracker.Snapshot().Paused {
+		t.Error("expected progress to report the pause")
+	}
+
+	g.Resume()
+	putCtx := <-started
+	// Cancelling mid-clip lets the clip in flight finish
+	cancel()
+	if putCtx.Err() != nil {
+		t.Error("clip in flight was cancelled")
+	}
+	close(release)
+
+	out := <-done
+	if !errors.Is(out.err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", out.err)
+	}
+	if out.res.Clips != 1 || out.res.Skipped != 2 {
+		t.Errorf("expected 1 archived and 2 skipped, got %+v", out.res.Tally)
+	}
+	if _, err := os.Stat(clips[0].Path); !os.IsNotExist(err) {
+		t.Error("expected the finished clip to be removed from the cam disk")
+	}
+	for _, c := range clips[1:] {
+		if _, err := os.Stat(c.Path); err != nil {
+			t.Errorf("expected %s to stay on the cam disk: %v", c.Key(), err)
+		}
+	}
+}
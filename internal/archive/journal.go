@@ -0,0 +1,205 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const JournalFile = "/mutable/teslausb/archive_journal"
+
+// Phase is how far an archive run got; each phase implies the previous ones.
+type Phase string
+
+const (
+	PhaseGadgetDisabled Phase = "gadget_disabled"
+	PhaseCamMounted     Phase = "cam_mounted"
+	PhaseArchiveMounted Phase = "archive_mounted"
+	PhaseTransferring   Phase = "transferring"
+
+	// Snapshot runs archive while the car keeps the drive, then disable
+	// the gadget only to remove what was archived
+	PhaseSnapshotTaken    Phase = "snapshot_taken"
+	PhaseSnapshotReleased Phase = "snapshot_released"
+)
+
+// Journal records an archive run on disk so a run cut short by power loss
+// can be cleaned up and finished on the next boot. It is one JSON object
+// per line; a nil *Journal ignores all writes.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+type journalEntry struct {
+	Phase Phase     `json:"phase,omitempty"`
+	Time  time.Time `json:"time,omitzero"`
+	Start string    `json:"start,omitempty"` // clip key now in flight
+	Dest  string    `json:"dest,omitempty"`
+	Done  string    `json:"done,omitempty"` // clip key confirmed archived
+	Drop  string    `json:"drop,omitempty"` // clip key a filter deleted, from a snapshot run
+	Size  int64     `json:"size,omitempty"`
+}
+
+// OpenJournal starts a new journal at path, replacing any previous one.
+func OpenJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	return &Journal{path: path, f: f}, nil
+}
+
+// SetPhase records that the run reached p, syncing it to disk.
+func (j *Journal) SetPhase(p Phase) {
+	j.write(journalEntry{Phase: p, Time: time.Now()}, true)
+}
+
+func (j *Journal) started(c Clip) {
+	j.write(journalEntry{Start: c.Key(), Dest: c.Dest}, false)
+}
+
+// confirmed records that c is safely archived and its source may go. A
+// lost confirmation only means the clip is sent again, so it isn't synced.
+func (j *Journal) confirmed(c Clip) {
+	j.write(journalEntry{Done: c.Key(), Size: c.Size}, false)
+}
+
+// dropped records that a filter deleted c in a run reading from a snapshot,
+// so the live image drops it along with the archived clips.
+func (j *Journal) dropped(c Clip) {
+	j.write(journalEntry{Drop: c.Key(), Size: c.Size}, false)
+}
+
+func (j *Journal) write(e journalEntry, sync bool) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return
+	}
+	data, _ := json.Marshal(e)
+	if _, err := j.f.Write(append(data, '\n')); err != nil {
+		log.Printf("journal: %v", err)
+		return
+	}
+	if sync {
+		j.f.Sync()
+	}
+}
+
+// Close ends a run that finished cleanly and removes the journal.
+func (j *Journal) Close() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return
+	}
+	j.f.Close()
+	j.f = nil
+	os.Remove(j.path)
+}
+
+// JournalState is what an interrupted run left behind.
+type JournalState struct {
+	Phase     Phase
+	Time      time.Time // when Phase was reached
+	InFlight  []string  // clip keys started but not confirmed
+	Confirmed map[string]int64
+	Dropped   map[string]int64 // deleted by a filter in a snapshot run
+}
+
+// ReadJournal returns the state of an interrupted run, or nil if the last
+// run finished cleanly. A line torn by power loss is ignored.
+func ReadJournal(path string) (*JournalState, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	st := &JournalState{Confirmed: map[string]int64{}, Dropped: map[string]int64{}}
+	var started []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		switch {
+		case e.Phase != "":
+			st.Phase, st.Time = e.Phase, e.Time
+		case e.Start != "":
+			started = append(started, e.Start)
+		case e.Done != "":
+			st.Confirmed[e.Done] = e.Size
+		case e.Drop != "":
+			st.Dropped[e.Drop] = e.Size
+		}
+	}
+	// A clip resent after failing verification is started twice
+	seen := map[string]bool{}
+	for _, key := range started {
+		if _, ok := st.Confirmed[key]; !ok && !seen[key] {
+			seen[key] = true
+			st.InFlight = append(st.InFlight, key)
+		}
+	}
+	return st, nil
+}
+
+// ToRemove returns how many clips RemoveConfirmed would remove if they are
+// all still on the cam disk.
+func (st *JournalState) ToRemove() int {
+	return len(st.Confirmed) + len(st.Dropped)
+}
+
+// RemoveConfirmed deletes sources under root that the run confirmed
+// archived, or dropped by filter, but didn't get to remove, so they aren't
+// sent again. A file is only removed if its size still matches. Returns the
+// number of clips and bytes removed.
+func (st *JournalState) RemoveConfirmed(root string) (int, int64) {
+	n := 0
+	var bytes int64
+	for key, size := range st.Confirmed {
+		if removeSized(root, key, size) {
+			n++
+			bytes += size
+		}
+	}
+	for key, size := range st.Dropped {
+		if removeSized(root, key, size) {
+			n++
+			bytes += size
+		}
+	}
+	return n, bytes
+}
+
+// removeSized removes root/key if it still has the given size.
+func removeSized(root, key string, size int64) bool {
+	p := filepath.Join(root, filepath.FromSlash(key))
+	info, err := os.Stat(p)
+	if err != nil || info.Size() != size {
+		return false
+	}
+	if err := os.Remove(p); err != nil {
+		log.Printf("journal: remove %s: %v", key, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplay(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/a.mp4", 10)
+	writeClip(t, root, "TeslaCam/SentryClips/ev/b.mp4", 20)
+	writeClip(t, root, "TeslaCam/SentryClips/ev/c.mp4", 30)
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.SetPhase(PhaseGadgetDisabled)
+	j.SetPhase(PhaseTransferring)
+
+	// Power is lost after a.mp4 is confirmed but before its source is
+	// removed, while b.mp4 is being sent
+	tracker := NewTracker(clips, nil)
+	tracker.journal = j
+	tracker.Start(clips[0])
+	tracker.Confirm(clips[0])
+	tracker.Start(clips[1])
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"done":"TeslaCam/Sen`)
+	f.Close()
+
+	st, err := ReadJournal(path)
+	if err != nil || st == nil {
+		t.Fatalf("expected interrupted run, got %v %v", st, err)
+	}
+	if st.Phase != PhaseTransferring {
+		t.Errorf("expected phase %s, got %s", PhaseTransferring, st.Phase)
+	}
+	if len(st.InFlight) != 1 || st.InFlight[0] != "TeslaCam/SentryClips/ev/b.mp4" {
+		t.Errorf("unexpected in-flight clips %v", st.InFlight)
+	}
+	if n, bytes := st.RemoveConfirmed(root); n != 1 || bytes != 10 {
+		t.Errorf("expected a.mp4 removed, got %d/%d", n, bytes)
+	}
+	if left := collectClips(root, []string{"TeslaCam/SentryClips"}); len(left) != 2 {
+		t.Errorf("expected 2 clips left to archive, got %d", len(left))
+	}
+
+	j.Close()
+	if st, _ := ReadJournal(path); st != nil {
+		t.Error("expected no journal after a clean finish")
+	}
+}
+
+func TestJournalConfirmedBeforeRemove(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SavedClips/ev/front.mp4", 10)
+	clips := collectClips(root, []string{"TeslaCam/SavedClips"})
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, _ := OpenJournal(path)
+	tracker := NewTracker(clips, nil)
+	tracker.journal = j
+	transferEach(context.Background(), clips, tracker, func(ctx context.Context, c Clip, t *Tracker) error { return nil })
+
+	st, _ := ReadJournal(path)
+	if _, ok := st.Confirmed["TeslaCam/SavedClips/ev/front.mp4"]; !ok || len(st.InFlight) != 0 {
+		t.Errorf("expected clip confirmed, got %+v", st)
+	}
+	// Sizes must match before a confirmed clip is deleted
+	writeClip(t, root, "TeslaCam/SavedClips/ev/front.mp4", 99)
+	if n, _ := st.RemoveConfirmed(root); n != 0 {
+		t.Error("a different file at the same path must be kept")
+	}
+}
+
+func TestJournalSnapshotRun(t *testing.T) {
+	snap, live := t.TempDir(), t.TempDir()
+	for _, root := range []string{snap, live} {
+		writeClip(t, root, "TeslaCam/SavedClips/ev/front.mp4", 10)
+		writeClip(t, root, "TeslaCam/SentryClips/ev/back.mp4", 20)
+		writeClip(t, root, "TeslaCam/SentryClips/ev/left.mp4", 30)
+	}
+	clips := collectClips(snap, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"})
+	keep, _ := applyFilters(clips, []filterDecision{
+		{rule: -1, action: FilterArchiveDelete},
+		{rule: 0, action: FilterDelete},
+		{rule: -1, action: FilterArchiveDelete},
+	}, func(c Clip) error { return nil })
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, _ := OpenJournal(path)
+	j.dropped(clips[1])
+	tracker := NewTracker(keep, nil)
+	tracker.journal = j
+	tracker.snapshot = true
+	res, err := transferEach(context.Background(), keep, tracker, func(ctx context.Context, c Clip, t *Tracker) error { return nil })
+	if err != nil || res.Clips != 2 {
+		t.Fatalf("transfer = %+v, %v", res, err)
+	}
+	if left := collectClips(snap, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"}); len(left) != 3 {
+		t.Errorf("a snapshot run must leave its sources, %d left", len(left))
+	}
+
+	// The car rewrote left.mp4 meanwhile, so the live image keeps it
+	writeClip(t, live, "TeslaCam/SentryClips/ev/left.mp4", 31)
+	st, _ := ReadJournal(path)
+	if n, bytes := st.RemoveConfirmed(live); n != 2 || bytes != 30 {
+		t.Errorf("expected front.mp4 and the dropped back.mp4 removed, got %d/%d", n, bytes)
+	}
+	left := collectClips(live, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"})
+	if len(left) != 1 || left[0].Key() != "TeslaCam/SentryClips/ev/left.mp4" {
+		t.Errorf("unexpected clips left on the live image %+v", left)
+	}
+	CleanClipDirs(live)
+	if _, err := os.Stat(filepath.Join(live, "TeslaCam/SavedClips/ev")); !os.IsNotExist(err) {
+		t.Error("expected the emptied event folder removed")
+	}
+}
+
+func TestJournalDroppedOnly(t *testing.T) {
+	// A snapshot run whose clips were all deleted by a filter is cut short
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/back.mp4", 20)
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	path := filepath.Join(t.TempDir(), "journal")
+	j, _ := OpenJournal(path)
+	j.SetPhase(PhaseSnapshotTaken)
+	j.dropped(clips[0])
+
+	st, err := ReadJournal(path)
+	if err != nil || st == nil {
+		t.Fatalf("expected interrupted run, got %v %v", st, err)
+	}
+	if len(st.Confirmed) != 0 || st.ToRemove() != 1 {
+		t.Fatalf("expected one clip to remove, got %d", st.ToRemove())
+	}
+	if n, _ := st.RemoveConfirmed(root); n != 1 {
+		t.Errorf("expected the dropped clip removed, got %d", n)
+	}
+}
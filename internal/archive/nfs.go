@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func init() {
+	Register("nfs", newNFS)
+}
+
+type nfsArchiver struct {
+	mountedShare
+	server string
+	share  string
+}
+
+func newNFS(cfg *config.Config) (Archiver, error) {
+	a := &nfsArchiver{server: cfg.NFS.Server, share: cfg.NFS.Share}
+	if a.server != "" && a.share == "" {
+		return nil, fmt.Errorf("nfs: share is required")
+	}
+	if a.share != "" && !strings.HasPrefix(a.share, "/") {
+		return nil, fmt.Errorf("nfs: share must be an absolute export path, got %q", a.share)
+	}
+	return a, nil
+}
+
+func (a *nfsArchiver) Name() string { return "nfs" }
+
+func (a *nfsArchiver) Describe() string {
+	return fmt.Sprintf("%s:%s", a.server, a.share)
+}
+
+func (a *nfsArchiver) Reachable() bool {
+	return tcpReachable(a.server, "2049")
+}
+
+// Prepare mounts the configured NFS share.
+func (a *nfsArchiver) Prepare(ctx context.Context) error {
+	os.MkdirAll(ArchiveMount, 0755)
+	source := a.Describe()
+	opts := "rw,noauto,nolock,proto=tcp,vers=3"
+	if err := exec.CommandContext(ctx, "mount", "-t", "nfs", source, ArchiveMount, "-o", opts).Run(); err != nil {
+		return fmt.Errorf("mount NFS %s: %w", source, err)
+	}
+	log.Printf("NFS mounted: %s", source)
+	return nil
+}
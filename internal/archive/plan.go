@@ -0,0 +1,220 @@
+package archive
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// selection is what an archive run would do with the cam disk: the clips
+// found, their event metadata and the filter decision for each clip.
+type selection struct {
+	clipDirs  []string
+	clips     []Clip
+	events    map[string]Event
+	decisions []filterDecision
+}
+
+// selectClips collects the clips under root that an archive run with cfg
+// would consider at now, sets their destinations and decides each by the
+// filter rules. Nothing is modified.
+func selectClips(root string, cfg *config.Config, now time.Time) selection {
+	sel := selection{clipDirs: []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"}}
+	if cfg != nil && cfg.Archive.RecentClips {
+		sel.clipDirs = append(sel.clipDirs, "TeslaCam/RecentClips")
+	}
+	sel.clips = collectClips(root, sel.clipDirs)
+	// Read event.json before the transfer removes it from the cam disk
+	sel.events = parseEvents(root, sel.clips)
+	var rules []config.FilterRule
+	if cfg != nil {
+		rules = cfg.Archive.Filters
+		applyTemplate(sel.clips, sel.events, cfg.Archive.PathTemplate, cfg)
+	}
+	sel.decisions = decideFilters(sel.clips, sel.events, rules, now)
+	return sel
+}
+
+// PlannedClip is a cam disk file and what the next archive run would do
+// with it.
+type PlannedClip struct {
+	Path   string `json:"path"`           // relative to the cam disk root
+	Dest   string `json:"dest,omitempty"` // destination path, for clips to archive
+	Size   int64  `json:"size"`
+	Action string `json:"action"`         // a filter action
+	Rule   int    `json:"rule,omitempty"` // 1-based filter rule that decided it, 0 if none matched
+}
+
+// PlannedEvent groups the planned clips of one event folder, or a loose clip.
+type PlannedEvent struct {
+	Path     string         `json:"path"`
+	Category string         `json:"category"`
+	Time     time.Time      `json:"time"`
+	Reason   string         `json:"reason,omitempty"`
+	City     string         `json:"city,omitempty"`
+	Actions  map[string]int `json:"actions"` // clips per action
+	Bytes    int64          `json:"bytes"`
+}
+
+// PlanTotal counts the clips and bytes given one action.
+type PlanTotal struct {
+	Clips int   `json:"clips"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Plan is what the next archive run would do, worked out without touching
+// the cam disk or any archive target. The "delete" total also counts what
+// retention and freeing space would then delete.
+type Plan struct {
+	Events []PlannedEvent       `json:"events"` // oldest first
+	Clips  []PlannedClip        `json:"clips"`
+	Totals map[string]PlanTotal `json:"totals"` // per action
+
+	// Retention is what retention would prune from the destination, as the
+	// last retention pass left it, once the clips are archived; FreeSpace
+	// is what would be deleted from the cam disk to free space after the run
+	Retention []PrunedEvent `json:"retention"`
+	FreeSpace []FreedClip   `json:"free_space"`
+
+	// BytesPerSec is the past throughput EstimatedSeconds is based on;
+	// both are 0 without any history
+	BytesPerSec      float64 `json:"bytes_per_sec"`
+	EstimatedSeconds float64 `json:"estimated_seconds"`
+}
+
+// PlanArchive runs the selection ArchiveClips would run on the cam disk
+// mounted at root with cfg, without archiving or deleting anything.
+func PlanArchive(root string, cfg *config.Config, now time.Time) Plan {
+	sel := selectClips(root, cfg, now)
+	plan := Plan{
+		Events: []PlannedEvent{},
+		Clips:  make([]PlannedClip, 0, len(sel.clips)),
+		Totals: map[string]PlanTotal{},
+
+		Retention: []PrunedEvent{},
+		FreeSpace: []FreedClip{},
+	}
+	byPath := map[string]*PlannedEvent{}
+	for i, c := range sel.clips {
+		d := sel.decisions[i]
+		pc := PlannedClip{Path: c.Key(), Size: c.Size, Action: d.action, Rule: d.rule + 1}
+		if archives(d.action) {
+			pc.Dest = c.Dest
+		}
+		plan.Clips = append(plan.Clips, pc)
+		t := plan.Totals[d.action]
+		t.Clips++
+		t.Bytes += c.Size
+		plan.Totals[d.action] = t
+
+		key := c.event()
+		if key == "" {
+			key = c.Key()
+		}
+		ev := byPath[key]
+		if ev == nil {
+			ev = &PlannedEvent{Path: key, Category: c.Category(), Time: c.ModTime, Actions: map[string]int{}}
+			if e, ok := sel.events[key]; ok {
+				ev.Reason, ev.City = e.Reason, e.City
+				if !e.Timestamp.IsZero() {
+					ev.Time = e.Timestamp
+				}
+			} else if c.event() != "" {
+				if t, err := time.ParseInLocation(eventFolderLayout, path.Base(key), time.Local); err == nil {
+					ev.Time = t
+				}
+			}
+			byPath[key] = ev
+		}
+		ev.Actions[d.action]++
+		ev.Bytes += c.Size
+	}
+	for _, ev := range byPath {
+		plan.Events = append(plan.Events, *ev)
+	}
+	sort.Slice(plan.Events, func(i, j int) bool {
+		if !plan.Events[i].Time.Equal(plan.Events[j].Time) {
+			return plan.Events[i].Time.Before(plan.Events[j].Time)
+		}
+		return plan.Events[i].Path < plan.Events[j].Path
+	})
+	plan.planRetention(sel, cfg, now)
+	plan.planFreeSpace(root, sel, cfg)
+	return plan
+}
+
+// planRetention adds the stored events retention would prune once the clips
+// to archive are stored. A dry run prunes nothing, so they are listed but
+// not counted as deleted.
+func (p *Plan) planRetention(sel selection, cfg *config.Config, now time.Time) {
+	if cfg == nil || len(cfg.Archive.Retention) == 0 {
+		return
+	}
+	lastStoredMu.Lock()
+	files := slices.Clone(lastStored)
+	lastStoredMu.Unlock()
+	for i, c := range sel.clips {
+		if archives(sel.decisions[i].action) {
+			files = append(files, StoredFile{Path: c.Dest, Size: c.Size, ModTime: c.ModTime})
+		}
+	}
+	p.Retention = append(p.Retention, planRetention(files, cfg.Archive.Retention, now)...)
+	if cfg.Archive.RetentionDryRun {
+		return
+	}
+	t := p.Totals[FilterDelete]
+	for _, ev := range p.Retention {
+		t.Clips += ev.Files
+		t.Bytes += ev.Bytes
+	}
+	p.Totals[FilterDelete] = t
+}
+
+// planFreeSpace adds the cam disk files ManageFreeSpace would delete once
+// the run has removed the clips it archives or deletes.
+func (p *Plan) planFreeSpace(root string, sel selection, cfg *config.Config) {
+	free, total, err := diskSpace(root)
+	if err != nil {
+		return
+	}
+	removed := map[string]bool{}
+	for i, c := range sel.clips {
+		if a := sel.decisions[i].action; a == FilterDelete || a == FilterArchiveDelete {
+			removed[c.Key()] = true
+			free += c.Size
+		}
+	}
+	need := freeSpaceNeed(cfg, free, total)
+	if need == nil {
+		return
+	}
+	var files []fileEntry
+	for _, f := range camFiles(root) {
+		if !removed[f.key] {
+			files = append(files, f)
+		}
+	}
+	protected, _ := ProtectedEvents()
+	archiveRecent := cfg != nil && cfg.Archive.RecentClips
+	p.FreeSpace = append(p.FreeSpace, planFreeSpace(files, need.Needed, need.Emergency, archiveRecent, protected)...)
+	t := p.Totals[FilterDelete]
+	for _, c := range p.FreeSpace {
+		t.Clips++
+		t.Bytes += c.Size
+	}
+	p.Totals[FilterDelete] = t
+}
+
+// Estimate sets the expected transfer time of the clips to archive from a
+// past throughput in bytes per second.
+func (p *Plan) Estimate(bytesPerSec float64) {
+	p.BytesPerSec = bytesPerSec
+	p.EstimatedSeconds = 0
+	if bytesPerSec > 0 {
+		bytes := p.Totals[FilterArchive].Bytes + p.Totals[FilterArchiveDelete].Bytes
+		p.EstimatedSeconds = float64(bytes) / bytesPerSec
+	}
+}
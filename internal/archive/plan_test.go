@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// fakeDiskSpace makes every cam disk report free of total bytes.
+func fakeDiskSpace(t *testing.T, free, total int64) {
+	orig := diskSpace
+	diskSpace = func(string) (int64, int64, error) { return free, total, nil }
+	t.Cleanup(func() { diskSpace = orig })
+}
+
+func TestPlanArchive(t *testing.T) {
+	root := t.TempDir()
+	writeEvent(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10", `{"timestamp":"2024-05-01T18:22:10","reason":"sentry_aware_object_detection"}`)
+	writeClip(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4", 100)
+	writeClip(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-back.mp4", 200)
+	writeEvent(t, root, "TeslaCam/SavedClips/2024-04-30_09-00-00", `{"reason":"user_interaction_honk"}`)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-04-30_09-00-00/2024-04-30_08-50-00-front.mp4", 300)
+	writeClip(t, root, "TeslaCam/RecentClips/2024-05-02_10-00-00-front.mp4", 50)
+
+	fakeDiskSpace(t, 100<<30, 100<<30)
+	cfg := &config.Config{}
+	cfg.Archive.PathTemplate = "{category}/{event}"
+	cfg.Archive.Filters = []config.FilterRule{
+		{Categories: []string{"SentryClips"}, Cameras: []string{"back"}, Action: FilterDelete},
+		{Categories: []string{"SavedClips"}, Action: FilterSkip},
+	}
+	plan := PlanArchive(root, cfg, time.Now())
+
+	// Nothing is touched, not even clips the rules delete
+	for _, rel := range []string{
+		"TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-back.mp4",
+		"TeslaCam/SavedClips/2024-04-30_09-00-00/event.json",
+	} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("plan modified the cam disk: %v", err)
+		}
+	}
+
+	if got := plan.Totals[FilterArchiveDelete]; got.Clips != 2 || got.Bytes != 100+int64(len(`{"timestamp":"2024-05-01T18:22:10","reason":"sentry_aware_object_detection"}`)) {
+		t.Errorf("archive totals = %+v", got)
+	}
+	if got := plan.Totals[FilterDelete]; got.Clips != 1 || got.Bytes != 200 {
+		t.Errorf("delete totals = %+v", got)
+	}
+	if got := plan.Totals[FilterSkip]; got.Clips != 2 {
+		t.Errorf("skip totals = %+v", got)
+	}
+	if len(plan.Clips) != 5 {
+		t.Errorf("expected 5 clips without RecentClips, got %d", len(plan.Clips))
+	}
+	for _, c := range plan.Clips {
+		if c.Path == "TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4" &&
+			(c.Dest != "SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4" || c.Rule != 0) {
+			t.Errorf("unexpected planned front clip %+v", c)
+		}
+		if c.Action == FilterDelete && c.Rule != 1 {
+			t.Errorf("expected rule 1 to delete %s, got %d", c.Path, c.Rule)
+		}
+	}
+
+	if len(plan.Events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", plan.Events)
+	}
+	saved, sentry := plan.Events[0], plan.Events[1]
+	if saved.Path != "TeslaCam/SavedClips/2024-04-30_09-00-00" || saved.Actions[FilterSkip] != 2 || saved.Reason != "user_interaction_honk" {
+		t.Errorf("unexpected saved event %+v", saved)
+	}
+	if sentry.Actions[FilterArchiveDelete] != 2 || sentry.Actions[FilterDelete] != 1 {
+		t.Errorf("unexpected sentry event %+v", sentry)
+	}
+
+	plan.Estimate(50)
+	if want := float64(plan.Totals[FilterArchiveDelete].Bytes) / 50; plan.EstimatedSeconds != want {
+		t.Errorf("estimated %v s, want %v", plan.EstimatedSeconds, want)
+	}
+	plan.Estimate(0)
+	if plan.EstimatedSeconds != 0 {
+		t.Errorf("expected no estimate without throughput, got %v", plan.EstimatedSeconds)
+	}
+}
+
+func TestPlanArchiveDeletions(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	event := "TeslaCam/SentryClips/" + now.Format(eventFolderLayout)
+	writeClip(t, root, event+"/"+now.Format(eventFolderLayout)+"-front.mp4", 100)
+	writeClip(t, root, "TeslaCam/RecentClips/2024-05-02_10-00-00-front.mp4", 50)
+	writeClip(t, root, "TeslaCam/RecentClips/2024-05-02_10-01-00-front.mp4", 60)
+	clips := collectClips(root, []string{"TeslaCam/RecentClips"})
+	for i, c := range clips {
+		os.Chtimes(c.Path, now, now.Add(time.Duration(i-2)*time.Hour))
+	}
+
+	// After archiving the sentry clip the cam disk is 40 bytes short of the
+	// 10% reserve, so the older RecentClips clip goes
+	fakeDiskSpace(t, 10<<30-100-40, 100<<30)
+	// The destination holds a sentry event past its age limit
+	lastStoredMu.Lock()
+	lastStored = []StoredFile{
+		{Path: "SentryClips/2024-01-01_00-00-00/2024-01-01_00-00-00-front.mp4", Size: 70},
+		{Path: "SentryClips/2024-01-01_00-00-00/event.json", Size: 5},
+	}
+	lastStoredMu.Unlock()
+	t.Cleanup(func() {
+		lastStoredMu.Lock()
+		lastStored = nil
+		lastStoredMu.Unlock()
+	})
+
+	cfg := &config.Config{}
+	cfg.Archive.PathTemplate = "{category}/{event}"
+	cfg.Archive.Retention = []config.RetentionRule{{Category: "SentryClips", MaxAgeDays: 30}}
+	plan := PlanArchive(root, cfg, now)
+
+	if got := plan.Totals[FilterArchiveDelete]; got.Clips != 1 || got.Bytes != 100 {
+		t.Errorf("archive totals = %+v", got)
+	}
+	if len(plan.Retention) != 1 || plan.Retention[0].Path != "SentryClips/2024-01-01_00-00-00" || plan.Retention[0].Reason != "age" {
+		t.Errorf("expected the old stored event pruned, got %+v", plan.Retention)
+	}
+	if len(plan.FreeSpace) != 1 || plan.FreeSpace[0].Path != "TeslaCam/RecentClips/2024-05-02_10-00-00-front.mp4" {
+		t.Errorf("expected the older RecentClips clip freed, got %+v", plan.FreeSpace)
+	}
+	if got := plan.Totals[FilterDelete]; got.Clips != 3 || got.Bytes != 75+50 {
+		t.Errorf("delete totals = %+v, want retention and free space", got)
+	}
+
+	// A dry run only reports what retention would prune
+	cfg.Archive.RetentionDryRun = true
+	plan = PlanArchive(root, cfg, now)
+	if got := plan.Totals[FilterDelete]; len(plan.Retention) != 1 || got.Clips != 1 || got.Bytes != 50 {
+		t.Errorf("dry run: retention %+v, delete totals %+v", plan.Retention, got)
+	}
+}
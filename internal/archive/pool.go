@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+	"github.com/teslausb-go/teslausb/internal/monitor"
+)
+
+// MaxWorkers bounds archive.workers.
+const MaxWorkers = 8
+
+// coolMargin is how far below the caution temperature extra workers stand
+// by, matching the temperature monitor's hysteresis.
+const coolMargin = 5.0
+
+var (
+	runningHot = cpuNearCaution
+	poolPoll   = 10 * time.Second // how often the pool rechecks the temperature
+)
+
+// categoryPriority orders clips for transfer: what the driver saved first,
+// then Sentry events, then the dashcam loop.
+var categoryPriority = map[string]int{"SavedClips": 0, "SentryClips": 1, "RecentClips": 2}
+
+// prioritize sorts clips by category priority, then newest event first.
+// Clips of one event stay together in name order.
+func prioritize(clips []Clip) {
+	when := func(c Clip) time.Time {
+		if ev := c.event(); ev != "" {
+			if t, err := time.ParseInLocation(eventFolderLayout, path.Base(ev), time.Local); err == nil {
+				return t
+			}
+		}
+		return c.ModTime
+	}
+	sort.SliceStable(clips, func(i, j int) bool {
+		a, b := clips[i], clips[j]
+		if pa, pb := categoryPriority[a.Category()], categoryPriority[b.Category()]; pa != pb {
+			return pa < pb
+		}
+		if ta, tb := when(a), when(b); !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+		return a.Key() < b.Key()
+	})
+}
+
+// cpuNearCaution reports whether the CPU is close enough to the caution
+// temperature that the pool should drop to one worker.
+func cpuNearCaution() bool {
+	cfg := config.Get()
+	if cfg == nil || cfg.Temperature.CautionCelsius <= 0 {
+		return false
+	}
+	return monitor.GetTemp() >= cfg.Temperature.CautionCelsius-coolMargin
+}
+
+// runPool calls work for items 0..n-1, handing them out in order to up to
+// workers goroutines. Workers beyond the first stand by while the CPU runs
+// hot, so the run carries on with one worker until it cools down.
+func runPool(n, workers int, work func(i int)) {
+	workers = max(1, min(workers, n))
+	var (
+		mu      sync.Mutex
+		next    int
+		hot     bool
+		polled  time.Time
+		drained = make(chan struct{}) // closed once every item is handed out
+	)
+	// take returns the next item for worker w, -1 if w must stand by, or
+	// false once there is nothing left
+	take := func(w int) (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= n {
+			return 0, false
+		}
+		if workers > 1 && time.Since(polled) >= poolPoll {
+			polled = time.Now()
+			if h := runningHot(); h != hot {
+				hot = h
+				if hot {
+					log.Printf("archive: CPU near caution temperature, continuing with one worker")
+				} else {
+					log.Printf("archive: CPU cooled down, resuming %d workers", workers)
+				}
+			}
+		}
+		if w > 0 && hot {
+			return -1, true
+		}
+		i := next
+		next++
+		if next == n {
+			close(drained)
+		}
+		return i, true
+	}
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := take(w)
+				if !ok {
+					return
+				}
+				if i < 0 {
+					select {
+					case <-time.After(poolPoll):
+					case <-drained:
+					}
+					continue
+				}
+				work(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrioritize(t *testing.T) {
+	clips := []Clip{
+		{Dir: "TeslaCam/RecentClips", RelPath: "2024-05-03_10-00-00-front.mp4"},
+		{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-01_18-22-10/front.mp4"},
+		{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-02_09-00-00/front.mp4"},
+		{Dir: "TeslaCam/SavedClips", RelPath: "2024-04-01_12-00-00/front.mp4"},
+		{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-02_09-00-00/back.mp4"},
+	}
+	prioritize(clips)
+	want := []string{
+		"TeslaCam/SavedClips/2024-04-01_12-00-00/front.mp4",
+		"TeslaCam/SentryClips/2024-05-02_09-00-00/back.mp4",
+		"TeslaCam/SentryClips/2024-05-02_09-00-00/front.mp4",
+		"TeslaCam/SentryClips/2024-05-01_18-22-10/front.mp4",
+		"TeslaCam/RecentClips/2024-05-03_10-00-00-front.mp4",
+	}
+	for i, c := range clips {
+		if c.Key() != want[i] {
+			t.Errorf("position %d: got %s, want %s", i, c.Key(), want[i])
+		}
+	}
+}
+
+// peakWorkers runs a pool over n items and returns how many ran at once.
+func peakWorkers(n, workers int) int {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	runPool(n, workers, func(int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	return peak
+}
+
+func TestRunPool(t *testing.T) {
+	defer func(hot func() bool, poll time.Duration) { runningHot, poolPoll = hot, poll }(runningHot, poolPoll)
+	poolPoll = time.Millisecond
+
+	runningHot = func() bool { return false }
+	if peak := peakWorkers(20, 4); peak < 2 || peak > 4 {
+		t.Errorf("expected up to 4 workers at once, got %d", peak)
+	}
+	runningHot = func() bool { return true }
+	if peak := peakWorkers(20, 4); peak != 1 {
+		t.Errorf("expected one worker while hot, got %d", peak)
+	}
+
+	var seen sync.Map
+	runPool(50, 3, func(i int) { seen.Store(i, true) })
+	for i := range 50 {
+		if _, ok := seen.Load(i); !ok {
+			t.Errorf("item %d not processed", i)
+		}
+	}
+}
+
+func TestTransferEachWorkers(t *testing.T) {
+	root := t.TempDir()
+	for _, cam := range []string{"front", "back", "left_repeater", "right_repeater", "left_pillar", "right_pillar"} {
+		writeClip(t, root, "TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-12-10-"+cam+".mp4", 100)
+	}
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	tracker := NewTracker(clips, nil)
+	tracker.workers = 3
+
+	res, err := transferEach(context.Background(), clips, tracker, func(ctx context.Context, c Clip, t *Tracker) error {
+		if c.Camera() == "back" {
+			return errTest
+		}
+		t.Add(c, c.Size)
+		return nil
+	})
+	tracker.Close()
+	if err == nil || res.Clips != 5 || res.Failed != 1 {
+		t.Errorf("expected 5 archived and 1 failed, got %+v (%v)", res.Tally, err)
+	}
+	if len(res.FailedFiles) != 1 || res.FailedFiles[0].Error != errTest.Error() {
+		t.Errorf("unexpected failed files %+v", res.FailedFiles)
+	}
+	if p := tracker.Snapshot(); p.FilesDone != 6 || p.FilesFailed != 1 || p.BytesDone != 600 {
+		t.Errorf("unexpected combined progress %+v", p)
+	}
+}
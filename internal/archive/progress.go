@@ -0,0 +1,247 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of a running (or the last finished) archive.
+type Progress struct {
+	Running     bool      `json:"running"`
+	FilesDone   int       `json:"files_done"`
+	FilesFailed int       `json:"files_failed"`
+	FilesTotal  int       `json:"files_total"`
+	BytesDone   int64     `json:"bytes_done"`
+	BytesTotal  int64     `json:"bytes_total"`
+	CurrentFile string    `json:"current_file"`           // most recently started
+	Active      []string  `json:"active_files,omitempty"` // every file in flight, with parallel workers
+	BytesPerSec float64   `json:"bytes_per_sec"`
+	ETASeconds  int       `json:"eta_seconds"`
+	StartedAt   time.Time `json:"started_at"`
+	Paused      bool      `json:"paused"`
+}
+
+const progressInterval = time.Second
+
+// Tracker accumulates per-file progress from a transfer engine and reports
+// throttled snapshots. A nil *Tracker is valid and ignores all updates.
+type Tracker struct {
+	mu         sync.Mutex
+	p          Progress
+	inFlight   map[string]int64 // bytes counted so far per clip key
+	failed     map[string]bool
+	resent     map[string]bool // sent again after a failed verification, already counted
+	report     func(Progress)
+	lastReport time.Time
+	journal    *Journal
+	gate       *Gate
+	workers    int  // transfers run at once, 0 or 1 for one at a time
+	snapshot   bool // reading from a snapshot: sources stay in place
+}
+
+// NewTracker starts tracking a run over clips. report may be nil.
+func NewTracker(clips []Clip, report func(Progress)) *Tracker {
+	t := &Tracker{
+		inFlight: map[string]int64{},
+		failed:   map[string]bool{},
+		resent:   map[string]bool{},
+		report:   report,
+	}
+	t.p.Running = true
+	t.p.StartedAt = time.Now()
+	t.p.FilesTotal = len(clips)
+	for _, c := range clips {
+		t.p.BytesTotal += c.Size
+	}
+	t.emit(true)
+	return t
+}
+
+// Wait holds the run between files while its gate is paused. It returns
+// ctx's error once the run is cancelled, and should be checked before
+// starting each file.
+func (t *Tracker) Wait(ctx context.Context) error {
+	if t == nil {
+		return ctx.Err()
+	}
+	if t.gate.Paused() {
+		t.emit(true)
+		defer t.emit(true)
+	}
+	return t.gate.wait(ctx)
+}
+
+// poolSize returns how many clips a transfer engine may move at once.
+func (t *Tracker) poolSize() int {
+	if t == nil {
+		return 1
+	}
+	return max(1, t.workers)
+}
+
+// Start marks c as the file currently being transferred.
+func (t *Tracker) Start(c Clip) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.p.CurrentFile = c.Key()
+	t.inFlight[c.Key()] = 0
+	t.mu.Unlock()
+	if !c.Keep {
+		t.journal.started(c)
+	}
+	t.emit(false)
+}
+
+// Confirm records that c is safely stored at the destination, just before
+// its source is removed.
+func (t *Tracker) Confirm(c Clip) {
+	if t == nil {
+		return
+	}
+	// A kept clip must not be removed when the journal is replayed
+	if !c.Keep {
+		t.journal.confirmed(c)
+	}
+}
+
+// Add records n more bytes of c transferred.
+func (t *Tracker) Add(c Clip, n int64) {
+	if t == nil || n == 0 {
+		return
+	}
+	t.mu.Lock()
+	if t.resent[c.Key()] {
+		t.mu.Unlock()
+		return
+	}
+	t.inFlight[c.Key()] += n
+	t.p.BytesDone += n
+	t.mu.Unlock()
+	t.emit(false)
+}
+
+// Finish marks c as processed. Whether it succeeded or not, its full size
+// counts toward BytesDone so the overall progress reaches 100%.
+func (t *Tracker) Finish(c Clip, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if counted, ok := t.inFlight[c.Key()]; ok {
+		t.p.BytesDone -= counted
+		delete(t.inFlight, c.Key())
+	}
+	if !t.resent[c.Key()] {
+		t.p.BytesDone += c.Size
+		t.p.FilesDone++
+	}
+	if err != nil && !t.failed[c.Key()] {
+		t.failed[c.Key()] = true
+		t.p.FilesFailed++
+	}
+	t.mu.Unlock()
+	t.emit(false)
+}
+
+// resend marks clips that are about to be sent again. Their transfer still
+// passes the gate and is journaled, but no longer counts toward progress.
+func (t *Tracker) resend(clips []Clip) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	for _, c := range clips {
+		t.resent[c.Key()] = true
+	}
+	t.mu.Unlock()
+}
+
+// Fail marks an already finished clip as failed, e.g. when its copy is
+// rejected by verification.
+func (t *Tracker) Fail(c Clip) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if !t.failed[c.Key()] {
+		t.failed[c.Key()] = true
+		t.p.FilesFailed++
+	}
+	t.mu.Unlock()
+	t.emit(false)
+}
+
+// Reader wraps r so bytes read from it count toward c's progress.
+func (t *Tracker) Reader(c Clip, r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &trackedReader{t: t, c: c, r: r}
+}
+
+// Close marks the run finished and reports the final snapshot.
+func (t *Tracker) Close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.p.Running = false
+	t.p.CurrentFile = ""
+	t.mu.Unlock()
+	t.emit(true)
+}
+
+// Snapshot returns the current progress with throughput and ETA filled in.
+func (t *Tracker) Snapshot() Progress {
+	if t == nil {
+		return Progress{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshotLocked()
+}
+
+func (t *Tracker) snapshotLocked() Progress {
+	p := t.p
+	p.Paused = p.Running && t.gate.Paused()
+	if len(t.inFlight) > 1 {
+		p.Active = slices.Sorted(maps.Keys(t.inFlight))
+	}
+	if elapsed := time.Since(p.StartedAt).Seconds(); elapsed > 0 {
+		p.BytesPerSec = float64(p.BytesDone) / elapsed
+	}
+	if p.Running && p.BytesPerSec > 0 {
+		p.ETASeconds = int(float64(p.BytesTotal-p.BytesDone) / p.BytesPerSec)
+	}
+	return p
+}
+
+func (t *Tracker) emit(force bool) {
+	t.mu.Lock()
+	if t.report == nil || (!force && time.Since(t.lastReport) < progressInterval) {
+		t.mu.Unlock()
+		return
+	}
+	t.lastReport = time.Now()
+	p := t.snapshotLocked()
+	t.mu.Unlock()
+	t.report(p)
+}
+
+type trackedReader struct {
+	t *Tracker
+	c Clip
+	r io.Reader
+}
+
+func (tr *trackedReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.t.Add(tr.c, int64(n))
+	return n, err
+}
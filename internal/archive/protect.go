@@ -0,0 +1,66 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// protectFile lists the events the user starred, so ManageFreeSpace never
+// deletes them. It lives on /mutable rather than the cam disk, which the car
+// may be writing to.
+var protectFile = "/mutable/teslausb/protected_events.json"
+
+var protectMu sync.Mutex
+
+// ProtectedEvents returns the protected event folders, e.g.
+// "TeslaCam/SavedClips/2024-05-01_18-22-10", sorted.
+func ProtectedEvents() ([]string, error) {
+	protectMu.Lock()
+	defer protectMu.Unlock()
+	return readProtected()
+}
+
+func readProtected() ([]string, error) {
+	data, err := os.ReadFile(protectFile)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events := []string{}
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+	slices.Sort(events)
+	return events, nil
+}
+
+// SetProtected stars or unstars an event folder.
+func SetProtected(event string, protect bool) error {
+	event = path.Clean(strings.TrimPrefix(event, "/"))
+	protectMu.Lock()
+	defer protectMu.Unlock()
+	events, err := readProtected()
+	if err != nil {
+		return err
+	}
+	i, found := slices.BinarySearch(events, event)
+	switch {
+	case protect && !found:
+		events = slices.Insert(events, i, event)
+	case !protect && found:
+		events = slices.Delete(events, i, i+1)
+	default:
+		return nil
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(protectFile, data, 0644)
+}
@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"path"
+	"strings"
+)
+
+// Tally counts what happened to clips in one or more archive runs.
+type Tally struct {
+	Events  int   `json:"events"` // event folders with at least one clip archived
+	Clips   int   `json:"clips"`
+	Bytes   int64 `json:"bytes"`
+	Skipped int   `json:"skipped"` // not attempted, e.g. the run was cancelled
+	Failed  int   `json:"failed"`
+
+	// Reasons counts archived events by event.json reason
+	Reasons map[string]int `json:"reasons,omitempty"`
+}
+
+// Add accumulates o into t.
+func (t *Tally) Add(o Tally) {
+	t.Events += o.Events
+	t.Clips += o.Clips
+	t.Bytes += o.Bytes
+	t.Skipped += o.Skipped
+	t.Failed += o.Failed
+	for reason, n := range o.Reasons {
+		if t.Reasons == nil {
+			t.Reasons = map[string]int{}
+		}
+		t.Reasons[reason] += n
+	}
+}
+
+// Result is the outcome of a single archive run: overall totals plus a
+// Tally per category ("SavedClips", "SentryClips", "RecentClips").
+type Result struct {
+	Target      string `json:"target,omitempty"`      // name of the archive target used
+	Backend     string `json:"backend,omitempty"`     // archive method, e.g. "nfs"
+	Destination string `json:"destination,omitempty"` // Archiver.Describe
+	Snapshot    bool   `json:"snapshot,omitempty"`    // read from a snapshot while the drive stayed attached
+	Tally
+	Categories map[string]Tally `json:"categories"`
+
+	// ArchivedEvents details each archived event folder with an event.json
+	ArchivedEvents []Event `json:"archived_events,omitempty"`
+
+	// FailedFiles lists the clips that failed and why, for backends that
+	// transfer clip by clip and for clips that failed verification
+	FailedFiles []FileError `json:"failed_files,omitempty"`
+
+	// Filtered counts what each filter rule decided, in rule order
+	Filtered []FilterOutcome `json:"filtered,omitempty"`
+
+	// Retention is what retention pruned on the destination after the run
+	Retention *RetentionReport `json:"retention,omitempty"`
+
+	events map[string]bool
+}
+
+// FileError is a clip that failed to archive.
+type FileError struct {
+	Path  string `json:"path"` // relative to the cam disk root
+	Error string `json:"error"`
+}
+
+type outcome int
+
+const (
+	archived  outcome = iota
+	unchanged         // already on the destination: archived, but no bytes sent
+	skipped
+	failed
+)
+
+// Category returns the cam directory a clip came from, e.g. "SentryClips".
+func (c Clip) Category() string {
+	return path.Base(c.Dir)
+}
+
+// event returns the event folder holding c, or "" for loose files such as
+// RecentClips on older firmware.
+func (c Clip) event() string {
+	dir, _, ok := strings.Cut(c.RelPath, "/")
+	if !ok {
+		return ""
+	}
+	return path.Join(c.Dir, dir)
+}
+
+func (r *Result) add(c Clip, o outcome) {
+	if r.Categories == nil {
+		r.Categories = map[string]Tally{}
+		r.events = map[string]bool{}
+	}
+	cat := r.Categories[c.Category()]
+	var delta Tally
+	switch o {
+	case archived, unchanged:
+		delta.Clips = 1
+		if o == archived {
+			delta.Bytes = c.Size
+		}
+		if ev := c.event(); ev != "" && !r.events[ev] {
+			r.events[ev] = true
+			delta.Events = 1
+		}
+	case skipped:
+		delta.Skipped = 1
+	case failed:
+		delta.Failed = 1
+	}
+	cat.Add(delta)
+	r.Categories[c.Category()] = cat
+	r.Tally.Add(delta)
+}
+
+func (r *Result) skipAll(clips []Clip) {
+	for _, c := range clips {
+		r.add(c, skipped)
+	}
+}
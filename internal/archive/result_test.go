@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResultPerCategory(t *testing.T) {
+	var res Result
+	res.add(Clip{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-01_18-22-10/front.mp4", Size: 100}, archived)
+	res.add(Clip{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-01_18-22-10/back.mp4", Size: 50}, archived)
+	res.add(Clip{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-02_08-00-00/front.mp4", Size: 10}, failed)
+	res.add(Clip{Dir: "TeslaCam/SavedClips", RelPath: "2024-05-03_09-10-11/front.mp4", Size: 20}, archived)
+	res.add(Clip{Dir: "TeslaCam/RecentClips", RelPath: "2024-05-03_09-09-00-front.mp4", Size: 30}, skipped)
+
+	sentry := res.Categories["SentryClips"]
+	if sentry.Events != 1 || sentry.Clips != 2 || sentry.Bytes != 150 || sentry.Failed != 1 {
+		t.Errorf("unexpected SentryClips tally %+v", sentry)
+	}
+	if recent := res.Categories["RecentClips"]; recent.Skipped != 1 || recent.Events != 0 {
+		t.Errorf("unexpected RecentClips tally %+v", recent)
+	}
+	if res.Events != 2 || res.Clips != 3 || res.Bytes != 170 || res.Skipped != 1 || res.Failed != 1 {
+		t.Errorf("unexpected totals %+v", res.Tally)
+	}
+}
+
+func TestTransferEachSkipsAfterCancel(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SavedClips/ev/a.mp4", 10)
+	writeClip(t, root, "TeslaCam/SavedClips/ev/b.mp4", 10)
+	writeClip(t, root, "TeslaCam/SavedClips/ev/c.mp4", 10)
+	clips := collectClips(root, []string{"TeslaCam/SavedClips"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res, err := transferEach(ctx, clips, nil, func(ctx context.Context, c Clip, t *Tracker) error {
+		cancel() // stop after the first clip is stored
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected context error")
+	}
+	if res.Clips != 1 || res.Skipped != 2 {
+		t.Errorf("expected 1 archived/2 skipped, got %+v", res.Tally)
+	}
+}
@@ -0,0 +1,280 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// clipCategories are the cam disk folders retention rules can apply to.
+var clipCategories = []string{"SavedClips", "SentryClips", "RecentClips"}
+
+// StoredFile is a file on the archive destination. Path is slash-separated
+// and relative to the archive root.
+type StoredFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// lastStored is what the destination held after the last retention pass,
+// so a plan can work out retention without reaching the destination.
+var (
+	lastStoredMu sync.Mutex
+	lastStored   []StoredFile
+)
+
+// Pruner is implemented by archivers that can list and delete what they
+// stored, so retention rules can be enforced on the destination.
+type Pruner interface {
+	// ListStored returns every file under the archive root.
+	ListStored(ctx context.Context) ([]StoredFile, error)
+	// RemoveStored deletes files, then the event folder dir (empty for a
+	// loose clip). Backends that can tell a directory is empty also remove
+	// parents left empty.
+	RemoveStored(ctx context.Context, dir string, files []string) error
+}
+
+// PrunedEvent is an archived event folder, or a loose clip, removed from the
+// destination by retention.
+type PrunedEvent struct {
+	Path     string    `json:"path"`
+	Category string    `json:"category"`
+	Time     time.Time `json:"time"`
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+	Reason   string    `json:"reason"` // "age" or "quota"
+
+	dir   string // event folder, empty for a loose clip
+	files []string
+}
+
+// RetentionReport is what one retention pass pruned, or would have pruned
+// in a dry run.
+type RetentionReport struct {
+	DryRun bool          `json:"dry_run"`
+	Events []PrunedEvent `json:"events"`
+	Bytes  int64         `json:"bytes"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// Summary describes the pass, e.g. "pruned 3 events (2 SentryClips, 1 RecentClips)".
+func (r *RetentionReport) Summary() string {
+	if r == nil || len(r.Events) == 0 {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, ev := range r.Events {
+		counts[ev.Category]++
+	}
+	var parts []string
+	for _, cat := range clipCategories {
+		if counts[cat] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[cat], cat))
+		}
+	}
+	verb := "pruned"
+	if r.DryRun {
+		verb = "would prune"
+	}
+	noun := "events"
+	if len(r.Events) == 1 {
+		noun = "event"
+	}
+	return fmt.Sprintf("%s %d %s (%s)", verb, len(r.Events), noun, strings.Join(parts, ", "))
+}
+
+// ValidateRetention checks retention rules name a known category once each,
+// and that the path template keeps categories apart on the destination.
+func ValidateRetention(rules []config.RetentionRule, tmpl string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, r := range rules {
+		if !slices.Contains(clipCategories, r.Category) {
+			return fmt.Errorf("retention: unknown category %q (use %s)", r.Category, strings.Join(clipCategories, ", "))
+		}
+		if seen[r.Category] {
+			return fmt.Errorf("retention: %s listed twice", r.Category)
+		}
+		seen[r.Category] = true
+		if r.MaxAgeDays < 0 || r.MaxSizeGB < 0 {
+			return fmt.Errorf("retention: %s limits must not be negative", r.Category)
+		}
+	}
+	if tmpl != "" && !strings.Contains(tmpl, "{category}") {
+		return fmt.Errorf("retention rules need {category} in the path template")
+	}
+	return nil
+}
+
+// storedEvents groups stored files into event folders and loose clips. The
+// category is the first path segment naming one, and the event is the first
+// segment after it that starts with a timestamp. Files outside any category
+// are left alone.
+func storedEvents(files []StoredFile) []PrunedEvent {
+	byPath := map[string]*PrunedEvent{}
+	var order []string
+	for _, f := range files {
+		segs := strings.Split(f.Path, "/")
+		cat := -1
+		for i, s := range segs[:len(segs)-1] {
+			if cat < 0 && slices.Contains(clipCategories, s) {
+				cat = i
+			}
+		}
+		if cat < 0 {
+			continue
+		}
+		key, dir := f.Path, ""
+		ts := f.ModTime
+		for i := cat + 1; i < len(segs); i++ {
+			if len(segs[i]) < len(eventFolderLayout) {
+				continue
+			}
+			t, err := time.ParseInLocation(eventFolderLayout, segs[i][:len(eventFolderLayout)], time.Local)
+			if err != nil {
+				continue
+			}
+			ts = t
+			if i < len(segs)-1 {
+				key = strings.Join(segs[:i+1], "/")
+				dir = key
+			}
+			break
+		}
+		ev := byPath[key]
+		if ev == nil {
+			ev = &PrunedEvent{Path: key, Category: segs[cat], Time: ts, dir: dir}
+			byPath[key] = ev
+			order = append(order, key)
+		}
+		ev.Files++
+		ev.Bytes += f.Size
+		ev.files = append(ev.files, f.Path)
+	}
+	events := make([]PrunedEvent, 0, len(order))
+	for _, key := range order {
+		events = append(events, *byPath[key])
+	}
+	return events
+}
+
+// planRetention returns the stored events that rules prune at now: those
+// older than the category's age limit, then the oldest remaining ones until
+// the category fits its size quota.
+func planRetention(files []StoredFile, rules []config.RetentionRule, now time.Time) []PrunedEvent {
+	events := storedEvents(files)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	var pruned []PrunedEvent
+	for _, rule := range rules {
+		var total int64
+		var keep []PrunedEvent
+		for _, ev := range events {
+			if ev.Category != rule.Category {
+				continue
+			}
+			if rule.MaxAgeDays > 0 && ev.Time.Before(now.AddDate(0, 0, -rule.MaxAgeDays)) {
+				ev.Reason = "age"
+				pruned = append(pruned, ev)
+				continue
+			}
+			keep = append(keep, ev)
+			total += ev.Bytes
+		}
+		quota := int64(rule.MaxSizeGB * (1 << 30))
+		for i := 0; rule.MaxSizeGB > 0 && total > quota && i < len(keep); i++ {
+			keep[i].Reason = "quota"
+			pruned = append(pruned, keep[i])
+			total -= keep[i].Bytes
+		}
+	}
+	return pruned
+}
+
+// ApplyRetention enforces the configured retention rules on the target
+// prepared by MountArchive. In a dry run nothing is deleted. Returns nil if
+// no rules are configured.
+func ApplyRetention(ctx context.Context) (*RetentionReport, error) {
+	cfg := config.Get()
+	if cfg == nil || len(cfg.Archive.Retention) == 0 {
+		return nil, nil
+	}
+	activeMu.Lock()
+	a := active
+	activeMu.Unlock()
+	if a.Archiver == nil {
+		return nil, fmt.Errorf("archive destination not mounted")
+	}
+	p, ok := a.Archiver.(Pruner)
+	if !ok {
+		return nil, fmt.Errorf("retention is not supported by %s", a.Archiver.Name())
+	}
+
+	files, err := p.ListStored(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retention: list %s: %w", a.Name, err)
+	}
+	report := &RetentionReport{DryRun: cfg.Archive.RetentionDryRun, Events: []PrunedEvent{}}
+	pruned := map[string]bool{}
+	var errs []error
+	for _, ev := range planRetention(files, cfg.Archive.Retention, time.Now()) {
+		if !report.DryRun {
+			if err := ctx.Err(); err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if err := p.RemoveStored(ctx, ev.dir, ev.files); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ev.Path, err))
+				continue
+			}
+		}
+		report.Events = append(report.Events, ev)
+		report.Bytes += ev.Bytes
+		for _, f := range ev.files {
+			pruned[f] = !report.DryRun
+		}
+	}
+	// Remember what is left for plans
+	var kept []StoredFile
+	for _, f := range files {
+		if !pruned[f.Path] {
+			kept = append(kept, f)
+		}
+	}
+	lastStoredMu.Lock()
+	lastStored = kept
+	lastStoredMu.Unlock()
+	if err := errors.Join(errs...); err != nil {
+		report.Error = err.Error()
+	}
+	if s := report.Summary(); s != "" {
+		log.Printf("retention on %s: %s, %d bytes", a.Name, s, report.Bytes)
+	}
+	return report, nil
+}
+
+// emptiedDirs returns the directories a prune of dir and files may leave
+// empty, deepest first, up to the archive root.
+func emptiedDirs(dir string, files []string) []string {
+	if dir == "" && len(files) > 0 {
+		dir = path.Dir(files[0])
+	}
+	var dirs []string
+	for dir != "" && dir != "." && dir != "/" {
+		dirs = append(dirs, dir)
+		dir = path.Dir(dir)
+	}
+	return dirs
+}
@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	mtime := now.Add(-time.Hour)
+	files := []StoredFile{
+		{Path: "TeslaCam/SentryClips/2024-04-01_10-00-00/front.mp4", Size: 100, ModTime: mtime},
+		{Path: "TeslaCam/SentryClips/2024-04-01_10-00-00/back.mp4", Size: 100, ModTime: mtime},
+		{Path: "TeslaCam/SentryClips/2024-05-30_10-00-00/front.mp4", Size: 100, ModTime: mtime},
+		{Path: "car/2024/05/SavedClips/2024-05-20_08-00-00/front.mp4", Size: 700, ModTime: mtime},
+		{Path: "car/2024/05/SavedClips/2024-05-25_08-00-00/front.mp4", Size: 600, ModTime: mtime},
+		{Path: "TeslaCam/RecentClips/2024-05-01_09-00-00-front.mp4", Size: 50, ModTime: mtime},
+		{Path: "TeslaCam/RecentClips/notes.txt", Size: 5, ModTime: now.AddDate(0, 0, -60)},
+		{Path: "other/2020-01-01_00-00-00/old.mp4", Size: 5, ModTime: mtime},
+	}
+	rules := []config.RetentionRule{
+		{Category: "SentryClips", MaxAgeDays: 30},
+		{Category: "SavedClips", MaxSizeGB: 1000.0 / (1 << 30)},
+		{Category: "RecentClips", MaxAgeDays: 7},
+	}
+	pruned := planRetention(files, rules, now)
+
+	want := []struct {
+		path, reason string
+		files        int
+	}{
+		{"TeslaCam/SentryClips/2024-04-01_10-00-00", "age", 2},
+		{"car/2024/05/SavedClips/2024-05-20_08-00-00", "quota", 1},
+		{"TeslaCam/RecentClips/notes.txt", "age", 1},
+		{"TeslaCam/RecentClips/2024-05-01_09-00-00-front.mp4", "age", 1},
+	}
+	if len(pruned) != len(want) {
+		t.Fatalf("pruned %d events, want %d: %+v", len(pruned), len(want), pruned)
+	}
+	for i, w := range want {
+		if pruned[i].Path != w.path || pruned[i].Reason != w.reason || pruned[i].Files != w.files {
+			t.Errorf("pruned[%d] = %s (%s, %d files), want %s (%s, %d files)",
+				i, pruned[i].Path, pruned[i].Reason, pruned[i].Files, w.path, w.reason, w.files)
+		}
+	}
+	if pruned[0].dir != pruned[0].Path || pruned[3].dir != "" {
+		t.Error("expected event folders to carry their dir and loose clips none")
+	}
+
+	report := &RetentionReport{DryRun: true, Events: pruned}
+	if got, want := report.Summary(), "would prune 4 events (1 SavedClips, 1 SentryClips, 2 RecentClips)"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestRemoveLocal(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/2024-04-01_10-00-00/front.mp4", 10)
+	writeClip(t, root, "TeslaCam/SentryClips/2024-04-01_10-00-00/event.json", 10)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-04-02_10-00-00/front.mp4", 10)
+
+	files, err := listLocal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := storedEvents(files)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	for _, ev := range events {
+		if ev.Category != "SentryClips" {
+			continue
+		}
+		if err := removeLocal(root, ev.dir, ev.files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "TeslaCam/SentryClips")); !os.IsNotExist(err) {
+		t.Error("expected emptied category folder removed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "TeslaCam/SavedClips/2024-04-02_10-00-00/front.mp4")); err != nil {
+		t.Errorf("other events must be kept: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rsyncOptions adjusts how rsyncClips runs rsync.
+type rsyncOptions struct {
+	keepSource  bool // leave sources for the caller to remove after verifying
+	ignoreTimes bool // resend files even if size and mtime already match
+	verifying   bool // the caller confirms each copy once it is verified
+}
+
+// rsyncPath is the rsync binary; tests replace it.
+var rsyncPath = "rsync"
+
+// rsyncGroup is one rsync run copying files from src to the same relative
+// paths below dst. Both dirs are relative to their roots.
+type rsyncGroup struct {
+	root     string // cam disk the clips are read from
+	src, dst string
+	clips    []Clip   // in transfer order
+	files    []string // files[i] is clips[i]'s path relative to src
+}
+
+// byFile returns the group's clips by path relative to src.
+func (g *rsyncGroup) byFile() map[string]Clip {
+	m := make(map[string]Clip, len(g.clips))
+	for i, c := range g.clips {
+		m[g.files[i]] = c
+	}
+	return m
+}
+
+// rsyncBatchFiles caps the files per rsync run. A running rsync is never
+// interrupted, so pause and cancel take effect between runs.
+const rsyncBatchFiles = 8
+
+// rsyncGroups splits clips into one rsync run per event folder (or per
+// cam directory for loose clips), of at most rsyncBatchFiles each, so the
+// run's workers can share the work. Groups and the clips in them keep the
+// order of clips. Clips kept on the cam disk get groups of their own.
+func rsyncGroups(clips []Clip) []*rsyncGroup {
+	type groupKey struct {
+		src, dst, event string
+		keep            bool
+	}
+	var groups []*rsyncGroup
+	byDirs := map[groupKey]*rsyncGroup{}
+	for _, c := range clips {
+		// Keep the longest tail of RelPath that Dest ends with
+		file := c.RelPath
+		for !strings.HasSuffix("/"+c.Dest, "/"+file) {
+			_, after, ok := strings.Cut(file, "/")
+			if !ok {
+				break
+			}
+			file = after
+		}
+		src := path.Join(c.Dir, strings.TrimSuffix(c.RelPath, file))
+		dst := path.Clean("/" + strings.TrimSuffix(c.Dest, file))[1:]
+		key := groupKey{src, dst, c.event(), c.Keep}
+		g, ok := byDirs[key]
+		if !ok {
+			root := strings.TrimSuffix(c.Path, filepath.FromSlash(c.Key()))
+			g = &rsyncGroup{root: root, src: src, dst: dst}
+			byDirs[key] = g
+			groups = append(groups, g)
+		}
+		g.clips = append(g.clips, c)
+		g.files = append(g.files, file)
+		if len(g.clips) == rsyncBatchFiles {
+			delete(byDirs, key)
+		}
+	}
+	return groups
+}
+
+// rsyncClips copies clips into dstRoot via rsync, removing source files
+// unless opts.keepSource is set. Each group of clips is fed to rsync via
+// --files-from, and its --progress output is parsed to report per-file
+// progress to t. Groups run in parallel on the run's workers, in order;
+// the run holds between groups while paused, and on cancel the groups
+// already running finish so no file is cut short.
+func rsyncClips(ctx context.Context, dstRoot string, clips []Clip, t *Tracker, opts rsyncOptions) (Result, error) {
+	var (
+		mu      sync.Mutex
+		res     Result
+		stopErr error // first error that ends the run early
+	)
+	record := func(c Clip, o outcome) {
+		mu.Lock()
+		res.add(c, o)
+		mu.Unlock()
+	}
+	stop := func(err error) {
+		mu.Lock()
+		if stopErr == nil {
+			stopErr = err
+		}
+		mu.Unlock()
+	}
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopErr != nil
+	}
+
+	groups := rsyncGroups(clips)
+	workers := t.poolSize()
+	running := max(1, min(workers, len(groups)))
+	runPool(len(groups), workers, func(i int) {
+		g := groups[i]
+		if stopped() {
+			for _, c := range g.clips {
+				record(c, skipped)
+			}
+			return
+		}
+		if err := t.Wait(ctx); err != nil {
+			for _, c := range g.clips {
+				record(c, skipped)
+			}
+			stop(err)
+			return
+		}
+		if err := rsyncGroupRun(ctx, dstRoot, g, t, opts, running, record); err != nil {
+			stop(err)
+		}
+	})
+	return res, stopErr
+}
+
+// rsyncGroupRun runs rsync for one group, recording each clip's outcome.
+// The bandwidth limit is split between the rsyncs running at once.
+func rsyncGroupRun(ctx context.Context, dstRoot string, g *rsyncGroup, t *Tracker, opts rsyncOptions, running int, record func(Clip, outcome)) error {
+	src := filepath.Join(g.root, g.src)
+	files := g.files
+	opts.keepSource = opts.keepSource || g.clips[0].Keep
+	skipAll := func() {
+		for _, c := range g.clips {
+			record(c, skipped)
+		}
+	}
+
+	dst := filepath.Join(dstRoot, g.dst) + "/"
+	os.MkdirAll(dst, 0755)
+
+	name := g.src
+	if ev := g.clips[0].event(); ev != "" {
+		name = ev
+	}
+	if g.src == g.dst {
+		log.Printf("archiving %s (%d files)", name, len(files))
+	} else {
+		log.Printf("archiving %s to %s (%d files)", name, g.dst, len(files))
+	}
+
+	// Build rsync command — --files-from paths are relative to src.
+	// No -h: progress byte counts must stay machine-readable.
+	args := []string{
+		"-avL",
+		"--progress",
+		"--no-o", "--no-g", // NFS root-squash workaround
+		"--no-perms",
+		"--omit-dir-times",
+	}
+	if !opts.keepSource {
+		args = append(args, "--remove-source-files")
+	}
+	if opts.ignoreTimes {
+		args = append(args, "--ignore-times")
+	}
+	if kb := bandwidthKB(); kb > 0 {
+		args = append(args, fmt.Sprintf("--bwlimit=%d", max(1, kb/running)))
+	}
+	args = append(args, "--files-from=-", src+"/", dst)
+
+	// Not bound to ctx: a batch is small, and letting it finish on cancel
+	// leaves no half-copied file behind
+	cmd := exec.Command(rsyncPath, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\n") + "\n")
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		skipAll()
+		return fmt.Errorf("rsync %s: %w", g.src, err)
+	}
+	if err := cmd.Start(); err != nil {
+		skipAll()
+		return fmt.Errorf("rsync %s: %w", g.src, err)
+	}
+	finished := parseRsyncProgress(stdout, g.byFile(), t)
+	err = cmd.Wait()
+
+	confirm := func(c Clip) {
+		if !opts.verifying {
+			t.Confirm(c)
+		}
+	}
+	// Settle clips rsync didn't report: a removed source (or a full-size
+	// copy when sources are kept) means the destination already had it
+	for i, c := range g.clips {
+		rel := files[i]
+		switch {
+		case finished[rel]:
+			confirm(c)
+			record(c, archived)
+		case sent(c, filepath.Join(dst, filepath.FromSlash(rel)), opts):
+			t.Finish(c, nil)
+			confirm(c)
+			record(c, unchanged)
+		case ctx.Err() != nil:
+			record(c, skipped)
+		default:
+			t.Finish(c, fmt.Errorf("not transferred"))
+			record(c, failed)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		// Exit code 24 = partial transfer (acceptable)
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 24 {
+			log.Println("rsync: partial transfer (some files vanished)")
+		} else {
+			return fmt.Errorf("rsync %s: %w", g.src, err)
+		}
+	}
+	return nil
+}
+
+func sent(c Clip, dst string, opts rsyncOptions) bool {
+	if !opts.keepSource {
+		_, err := os.Stat(c.Path)
+		return os.IsNotExist(err)
+	}
+	info, err := os.Stat(dst)
+	return err == nil && info.Size() == c.Size
+}
+
+// parseRsyncProgress reads rsync -v --progress output, where each file name
+// line is followed by \r-separated updates such as
+// "  1,234,567 100%   10.00MB/s    0:00:01 (xfr#1, to-chk=3/5)".
+// Other lines are passed through to stdout. Returns the keys of clips
+// rsync reported as fully transferred.
+func parseRsyncProgress(r io.Reader, clips map[string]Clip, t *Tracker) map[string]bool {
+	finished := map[string]bool{}
+	var cur *Clip
+	var curKey string
+	var curBytes int64
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(scanLinesOrCR)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if c, ok := clips[line]; ok {
+			cur, curKey, curBytes = &c, line, 0
+			t.Start(c)
+			fmt.Fprintln(os.Stdout, line)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.HasSuffix(fields[1], "%") {
+			if cur == nil {
+				continue
+			}
+			digits := strings.NewReplacer(",", "", ".", "").Replace(fields[0])
+			if n, err := strconv.ParseInt(digits, 10, 64); err == nil && n > curBytes {
+				t.Add(*cur, n-curBytes)
+				curBytes = n
+			}
+			if strings.Contains(line, "xfr#") {
+				t.Finish(*cur, nil)
+				finished[curKey] = true
+				cur = nil
+			}
+			continue
+		}
+		fmt.Fprintln(os.Stdout, line)
+	}
+	return finished
+}
+
+// scanLinesOrCR is a bufio.SplitFunc that splits on \n or \r, since rsync
+// redraws progress lines with carriage returns.
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
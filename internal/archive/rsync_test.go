@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRsyncProgress(t *testing.T) {
+	clips := map[string]Clip{
+		"ev/front.mp4": {Dir: "TeslaCam/SavedClips", RelPath: "ev/front.mp4", Size: 2000000},
+		"ev/back.mp4":  {Dir: "TeslaCam/SavedClips", RelPath: "ev/back.mp4", Size: 1000},
+	}
+	out := "sending incremental file list\n" +
+		"ev/\n" +
+		"ev/front.mp4\n" +
+		"         32,768   1%    0.00kB/s    0:00:00\r" +
+		"      2,000,000 100%   10.00MB/s    0:00:01 (xfr#1, to-chk=1/3)\n" +
+		"ev/back.mp4\n" +
+		"            512  51%    0.00kB/s    0:00:00\r" +
+		"rsync: write failed on \"ev/back.mp4\"\n" +
+		"\nsent 2,001,234 bytes  received 35 bytes\n"
+
+	var last Progress
+	tracker := NewTracker([]Clip{clips["ev/front.mp4"], clips["ev/back.mp4"]}, func(p Progress) { last = p })
+	finished := parseRsyncProgress(strings.NewReader(out), clips, tracker)
+
+	if !finished["ev/front.mp4"] || finished["ev/back.mp4"] {
+		t.Errorf("expected only front.mp4 finished, got %v", finished)
+	}
+	p := tracker.Snapshot()
+	if p.FilesDone != 1 {
+		t.Errorf("expected 1 file done, got %d", p.FilesDone)
+	}
+	if p.BytesDone != 2000512 {
+		t.Errorf("expected 2000512 bytes done, got %d", p.BytesDone)
+	}
+	if p.CurrentFile != "TeslaCam/SavedClips/ev/back.mp4" {
+		t.Errorf("unexpected current file %s", p.CurrentFile)
+	}
+	tracker.Close()
+	if last.Running {
+		t.Error("expected final report to mark the run finished")
+	}
+}
+
+func TestTrackerFinishCountsFailedFiles(t *testing.T) {
+	c := Clip{Dir: "TeslaCam/SentryClips", RelPath: "ev/left.mp4", Size: 100}
+	tracker := NewTracker([]Clip{c}, nil)
+	tracker.Start(c)
+	tracker.Add(c, 40)
+	tracker.Finish(c, errTest)
+	p := tracker.Snapshot()
+	if p.BytesDone != 100 || p.FilesDone != 1 || p.FilesFailed != 1 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+	var nilTracker *Tracker
+	nilTracker.Start(c) // must not panic
+}
+
+func TestParseRsyncProgressTemplate(t *testing.T) {
+	// {event}/{category} moves the event folder out of the rsync group's
+	// file paths, so rsync reports just the file name
+	c := Clip{
+		Dir:     "TeslaCam/SentryClips",
+		RelPath: "2024-05-01_18-22-10/2024-05-01_18-22-10-front.mp4",
+		Path:    "/mnt/cam/TeslaCam/SentryClips/2024-05-01_18-22-10/2024-05-01_18-22-10-front.mp4",
+		Dest:    "2024-05-01_18-22-10/SentryClips/2024-05-01_18-22-10-front.mp4",
+		Size:    1000,
+	}
+	groups := rsyncGroups([]Clip{c})
+	if len(groups) != 1 {
+		t.Fatalf("expected one group, got %d", len(groups))
+	}
+	clips := groups[0].byFile()
+	out := "2024-05-01_18-22-10-front.mp4\n" +
+		"          1,000 100%   10.00MB/s    0:00:01 (xfr#1, to-chk=0/1)\n"
+
+	tracker := NewTracker([]Clip{c}, nil)
+	finished := parseRsyncProgress(strings.NewReader(out), clips, tracker)
+	for rel := range clips {
+		if !finished[rel] {
+			t.Errorf("%s not reported finished: %v", rel, finished)
+		}
+	}
+	if p := tracker.Snapshot(); p.FilesDone != 1 || p.BytesDone != 1000 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+}
+
+// fakeRsync replaces rsync with a script that copies the --files-from list
+// and prints -v --progress output. Like rsync, it skips files whose copy
+// already has the same size unless --ignore-times is given.
+func fakeRsync(t *testing.T) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "rsync")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+remove= ignore=
+for a; do
+	case "$a" in
+	--remove-source-files) remove=1 ;;
+	--ignore-times) ignore=1 ;;
+	esac
+done
+eval src=\${$(($# - 1))}
+eval dst=\${$#}
+while IFS= read -r f; do
+	[ -n "$f" ] || continue
+	size=$(wc -c < "$src$f" | tr -d ' ')
+	if [ -z "$ignore" ] && [ -f "$dst$f" ] && [ "$(wc -c < "$dst$f" | tr -d ' ')" = "$size" ]; then
+		[ -n "$remove" ] && rm "$src$f"
+		continue
+	fi
+	mkdir -p "$(dirname "$dst$f")"
+	cp -p "$src$f" "$dst$f"
+	echo "$f"
+	echo "$size 100% 1.00MB/s 0:00:01 (xfr#1, to-chk=0/1)"
+	[ -n "$remove" ] && rm "$src$f"
+done
+exit 0
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := rsyncPath
+	rsyncPath = script
+	t.Cleanup(func() { rsyncPath = orig })
+}
+
+func TestRsyncSnapshotConfirms(t *testing.T) {
+	fakeRsync(t)
+	snap, dst := t.TempDir(), t.TempDir()
+	writeClip(t, snap, "TeslaCam/SavedClips/ev/front.mp4", 10)
+	writeClip(t, snap, "TeslaCam/SentryClips/ev/back.mp4", 20)
+	clips := collectClips(snap, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"})
+	for i := range clips {
+		clips[i].Dest = clips[i].Key()
+	}
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, _ := OpenJournal(path)
+	tracker := NewTracker(clips, nil)
+	tracker.journal = j
+	tracker.snapshot = true
+	res, err := rsyncClips(context.Background(), dst, clips, tracker, rsyncOptions{keepSource: tracker.keepsSources()})
+	if err != nil || res.Clips != 2 {
+		t.Fatalf("rsync = %+v, %v", res, err)
+	}
+	if left := collectClips(snap, []string{"TeslaCam/SavedClips", "TeslaCam/SentryClips"}); len(left) != 2 {
+		t.Errorf("a snapshot run must leave its sources, %d left", len(left))
+	}
+	st, _ := ReadJournal(path)
+	if st == nil || len(st.Confirmed) != 2 {
+		t.Fatalf("expected both clips confirmed for the live image, got %+v", st)
+	}
+}
+
+func TestRsyncUpToDateAddsNoBytes(t *testing.T) {
+	fakeRsync(t)
+	src, dst := t.TempDir(), t.TempDir()
+	writeClip(t, src, "TeslaCam/SavedClips/ev/front.mp4", 10)
+	writeClip(t, src, "TeslaCam/SavedClips/ev/back.mp4", 20)
+	// An earlier run copied back.mp4 but lost power before removing it
+	writeClip(t, dst, "TeslaCam/SavedClips/ev/back.mp4", 20)
+	clips := collectClips(src, []string{"TeslaCam/SavedClips"})
+	for i := range clips {
+		clips[i].Dest = clips[i].Key()
+	}
+
+	res, err := rsyncClips(context.Background(), dst, clips, NewTracker(clips, nil), rsyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Clips != 2 || res.Bytes != 10 || res.Events != 1 {
+		t.Errorf("expected 2 clips but only front.mp4's 10 bytes, got %+v", res.Tally)
+	}
+	if left := collectClips(src, []string{"TeslaCam/SavedClips"}); len(left) != 0 {
+		t.Errorf("expected both sources removed, %d left", len(left))
+	}
+}
+
+func TestRsyncKeepsArchivedClips(t *testing.T) {
+	fakeRsync(t)
+	src, dst := t.TempDir(), t.TempDir()
+	writeClip(t, src, "TeslaCam/SavedClips/ev/front.mp4", 10)
+	writeClip(t, src, "TeslaCam/SavedClips/ev/back.mp4", 20)
+	clips := collectClips(src, []string{"TeslaCam/SavedClips"})
+	for i := range clips {
+		clips[i].Dest = clips[i].Key()
+		clips[i].Keep = clips[i].RelPath == "ev/front.mp4"
+	}
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, _ := OpenJournal(path)
+	tracker := NewTracker(clips, nil)
+	tracker.journal = j
+	res, err := rsyncClips(context.Background(), dst, clips, tracker, rsyncOptions{})
+	if err != nil || res.Clips != 2 {
+		t.Fatalf("rsync = %+v, %v", res, err)
+	}
+	left := collectClips(src, []string{"TeslaCam/SavedClips"})
+	if len(left) != 1 || left[0].RelPath != "ev/front.mp4" {
+		t.Errorf("expected only the kept front.mp4 left, got %+v", left)
+	}
+	for _, c := range clips {
+		if _, err := os.Stat(filepath.Join(dst, c.Dest)); err != nil {
+			t.Errorf("%s not archived: %v", c.Key(), err)
+		}
+	}
+	st, _ := ReadJournal(path)
+	if st == nil || len(st.Confirmed) != 1 || st.Confirmed["TeslaCam/SavedClips/ev/back.mp4"] == 0 {
+		t.Errorf("a kept clip must stay out of the journal, got %+v", st)
+	}
+}
@@ -0,0 +1,485 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func init() {
+	Register("s3", newS3)
+}
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3PartSize      = 8 * 1024 * 1024 // multipart chunk size (S3 minimum is 5MB)
+	emptySHA256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+type s3Archiver struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	partSize  int64
+	client    *http.Client
+}
+
+func newS3(cfg *config.Config) (Archiver, error) {
+	c := cfg.S3
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, fmt.Errorf("s3: endpoint and bucket are required")
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint must be an http(s) URL, got %q", c.Endpoint)
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, fmt.Errorf("s3: access_key and secret_key are required")
+	}
+	region := c.Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	return &s3Archiver{
+		endpoint:  u,
+		region:    region,
+		bucket:    c.Bucket,
+		prefix:    strings.Trim(c.Prefix, "/"),
+		accessKey: c.AccessKey,
+		secretKey: c.SecretKey,
+		pathStyle: c.PathStyle,
+		partSize:  s3PartSize,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (a *s3Archiver) Name() string { return "s3" }
+
+func (a *s3Archiver) Describe() string {
+	return fmt.Sprintf("s3://%s/%s (%s)", a.bucket, a.prefix, a.endpoint.Host)
+}
+
+func (a *s3Archiver) Reachable() bool {
+	port := a.endpoint.Port()
+	if port == "" {
+		port = "443"
+		if a.endpoint.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return tcpReachable(a.endpoint.Hostname(), port)
+}
+
+// Prepare checks that the bucket exists and the credentials can reach it.
+func (a *s3Archiver) Prepare(ctx context.Context) error {
+	resp, err := a.do(ctx, http.MethodHead, "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("s3: bucket %s: %w", a.bucket, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (a *s3Archiver) Transfer(ctx context.Context, clips []Clip, t *Tracker) (Result, error) {
+	return transferEach(ctx, clips, t, a.put)
+}
+
+func (a *s3Archiver) Teardown() {}
+
+// ListStored lists the objects under the prefix with ListObjectsV2.
+func (a *s3Archiver) ListStored(ctx context.Context) ([]StoredFile, error) {
+	prefix := ""
+	if a.prefix != "" {
+		prefix = a.prefix + "/"
+	}
+	var files []StoredFile
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := a.do(ctx, http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", a.bucket, err)
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", a.bucket, err)
+		}
+		for _, obj := range page.Contents {
+			files = append(files, StoredFile{
+				Path:    strings.TrimPrefix(obj.Key, prefix),
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return files, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// RemoveStored deletes each object; S3 has no directories to clean up.
+func (a *s3Archiver) RemoveStored(ctx context.Context, dir string, files []string) error {
+	for _, f := range files {
+		key := f
+		if a.prefix != "" {
+			key = path.Join(a.prefix, f)
+		}
+		resp, err := a.do(ctx, http.MethodDelete, key, nil, nil)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (a *s3Archiver) objectKey(c Clip) string {
+	if a.prefix == "" {
+		return c.Dest
+	}
+	return path.Join(a.prefix, c.Dest)
+}
+
+// put uploads a clip and confirms the stored object's size and ETag match
+// the data read from the clip before reporting success. The ETag is the MD5
+// of the data, or for multipart uploads the MD5 of the part MD5s followed
+// by the part count.
+func (a *s3Archiver) put(ctx context.Context, c Clip, t *Tracker) error {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	key := a.objectKey(c)
+	var etag string
+	if c.Size <= a.partSize {
+		etag, err = a.putObject(ctx, key, throttle(ctx, f))
+		if err == nil {
+			t.Add(c, c.Size)
+		}
+	} else {
+		etag, err = a.putMultipart(ctx, key, throttle(ctx, f), func(n int64) { t.Add(c, n) })
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", key, err)
+	}
+	resp.Body.Close()
+	if resp.ContentLength != c.Size {
+		return fmt.Errorf("verify %s: size %d, expected %d", key, resp.ContentLength, c.Size)
+	}
+	if got := trimETag(resp.Header.Get("ETag")); got != etag {
+		return fmt.Errorf("verify %s: etag %s, expected %s", key, got, etag)
+	}
+	return nil
+}
+
+// putObject uploads r in one request and returns the ETag its data should
+// be stored with.
+func (a *s3Archiver) putObject(ctx context.Context, key string, r io.Reader) (string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.do(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return "", fmt.Errorf("put %s: %w", key, err)
+	}
+	resp.Body.Close()
+	sum := md5.Sum(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putMultipart uploads r in partSize chunks, calling sent after each part,
+// and returns the ETag its data should be stored with.
+func (a *s3Archiver) putMultipart(ctx context.Context, key string, r io.Reader, sent func(n int64)) (string, error) {
+	resp, err := a.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("create multipart %s: %w", key, err)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return "", fmt.Errorf("create multipart %s: no upload id: %v", key, err)
+	}
+	uploadID := initiated.UploadID
+
+	abort := func() {
+		// Use a fresh context so cancellation still cleans up the upload
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if resp, err := a.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	var parts []s3CompletedPart
+	var sums []byte // MD5 of each part, for the expected ETag
+	buf := make([]byte, a.partSize)
+	for partNum := 1; ; partNum++ {
+		n, readErr := io.ReadFull(r, buf)
+		if n == 0 {
+			break
+		}
+		q := url.Values{"partNumber": {strconv.Itoa(partNum)}, "uploadId": {uploadID}}
+		resp, err := a.do(ctx, http.MethodPut, key, q, buf[:n])
+		if err != nil {
+			abort()
+			return "", fmt.Errorf("upload part %d of %s: %w", partNum, key, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, s3CompletedPart{PartNumber: partNum, ETag: resp.Header.Get("ETag")})
+		sum := md5.Sum(buf[:n])
+		sums = append(sums, sum[:]...)
+		sent(int64(n))
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			abort()
+			return "", readErr
+		}
+	}
+
+	complete, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		abort()
+		return "", err
+	}
+	resp, err = a.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, complete)
+	if err != nil {
+		abort()
+		return "", fmt.Errorf("complete multipart %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	// CompleteMultipartUpload can fail with a 200 status and an <Error> body
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		Code    string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		abort()
+		return "", fmt.Errorf("complete multipart %s: %w", key, err)
+	}
+	if result.XMLName.Local == "Error" {
+		abort()
+		return "", fmt.Errorf("complete multipart %s: %s", key, result.Code)
+	}
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(parts)), nil
+}
+
+// do sends a signed request for key (or the bucket itself when key is empty)
+// and returns an error for non-2xx responses.
+func (a *s3Archiver) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *a.endpoint
+	objPath := "/" + key
+	if a.pathStyle {
+		objPath = "/" + a.bucket + objPath
+	} else {
+		u.Host = a.bucket + "." + u.Host
+	}
+	if key == "" {
+		objPath = strings.TrimSuffix(objPath, "/")
+		if objPath == "" {
+			objPath = "/"
+		}
+	}
+	u.Path = objPath
+	u.RawPath = awsURIEncode(objPath, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := emptySHA256
+	if len(body) > 0 {
+		sum := md5.Sum(body)
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		payloadHash = sha256Hex(body)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, a.accessKey, a.secretKey, a.region, "s3", payloadHash, time.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Err)
+		if s3Err.Code != "" {
+			return nil, fmt.Errorf("HTTP %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func trimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req,
+// signing the host and every header already set on the request.
+func signV4(req *http.Request, accessKey, secretKey, region, service, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	names := []string{"host"}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "authorization" {
+			continue
+		}
+		vals := make([]string, len(v))
+		for i, val := range v {
+			vals[i] = strings.Join(strings.Fields(val), " ")
+		}
+		headers[lk] = strings.Join(vals, ",")
+		names = append(names, lk)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, n := range names {
+		canonicalHeaders.WriteString(n + ":" + headers[n] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key as SigV4 requires.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything except unreserved characters,
+// optionally leaving '/' intact for object paths.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,316 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// fakeS3 is a minimal in-process S3 server supporting the calls the s3
+// backend makes: HeadBucket, PutObject, HeadObject, multipart uploads,
+// ListObjectsV2 (unpaginated) and DeleteObject.
+type fakeS3 struct {
+	mu        sync.Mutex
+	bucket    string
+	objects   map[string][]byte
+	etags     map[string]string
+	uploads   map[string]map[int][]byte
+	nextID    int
+	shortHead bool // report a truncated Content-Length on HEAD
+	corrupt   bool // store a damaged copy of each object
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:  bucket,
+		objects: map[string][]byte{},
+		etags:   map[string]string{},
+		uploads: map[string]map[int][]byte{},
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	q := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet && q.Get("list-type") == "2":
+		fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated>")
+		for k, data := range f.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2024-05-01T00:00:00.000Z</LastModified></Contents>", k, len(data))
+			}
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		size := len(data)
+		if f.shortHead {
+			size--
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(size))
+		w.Header().Set("ETag", `"`+f.etags[key]+`"`)
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.nextID++
+		id := fmt.Sprintf("upload-%d", f.nextID)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		var n int
+		fmt.Sscan(q.Get("partNumber"), &n)
+		f.uploads[q.Get("uploadId")][n] = body
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var req struct {
+			Parts []s3CompletedPart `xml:"Part"`
+		}
+		xml.Unmarshal(body, &req)
+		parts := f.uploads[q.Get("uploadId")]
+		var data, sums []byte
+		for _, p := range req.Parts {
+			data = append(data, parts[p.PartNumber]...)
+			sum := md5.Sum(parts[p.PartNumber])
+			sums = append(sums, sum[:]...)
+		}
+		delete(f.uploads, q.Get("uploadId"))
+		sum := md5.Sum(sums)
+		f.objects[key] = data
+		f.etags[key] = fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(req.Parts))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%s"</ETag></CompleteMultipartUploadResult>`, f.etags[key])
+		if f.corrupt {
+			f.etags[key] = "0" + f.etags[key][1:]
+		}
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		delete(f.etags, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		if f.corrupt {
+			body = append(bytes.Clone(body[1:]), 0)
+			sum = md5.Sum(body)
+		}
+		f.objects[key] = body
+		f.etags[key] = hex.EncodeToString(sum[:])
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newTestS3(t *testing.T, fake *fakeS3) *s3Archiver {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	a, err := newS3(&config.Config{S3: config.S3{
+		Endpoint:  srv.URL,
+		Bucket:    fake.bucket,
+		Prefix:    "/car/",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		PathStyle: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3a := a.(*s3Archiver)
+	s3a.partSize = 1024
+	return s3a
+}
+
+func writeClip(t *testing.T, root, rel string, size int) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	os.MkdirAll(filepath.Dir(p), 0755)
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestS3Transfer(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4", 3000)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-01_18-22-10/event.json", 100)
+
+	fake := newFakeS3("teslacam")
+	a := newTestS3(t, fake)
+	if err := a.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	clips := collectClips(root, []string{"TeslaCam/SavedClips"})
+	res, err := a.Transfer(context.Background(), clips, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Clips != 2 || res.Bytes != 3100 || res.Events != 1 {
+		t.Errorf("expected 2 clips/3100 bytes/1 event, got %+v", res.Tally)
+	}
+	if got := len(fake.objects["car/TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4"]); got != 3000 {
+		t.Errorf("expected multipart object of 3000 bytes, got %d", got)
+	}
+	if !strings.HasSuffix(fake.etags["car/TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4"], "-3") {
+		t.Errorf("expected 3-part multipart etag, got %s", fake.etags["car/TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4"])
+	}
+	if len(collectClips(root, []string{"TeslaCam/SavedClips"})) != 0 {
+		t.Error("expected sources removed after verified upload")
+	}
+}
+
+func TestS3TransferKeepsSourceOnVerifyFailure(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/back.mp4", 500)
+
+	fake := newFakeS3("teslacam")
+	fake.shortHead = true
+	a := newTestS3(t, fake)
+
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	res, err := a.Transfer(context.Background(), clips, nil)
+	if err == nil {
+		t.Fatal("expected verification error")
+	}
+	if res.Clips != 0 || res.Failed != 1 {
+		t.Errorf("expected 1 failed clip, got %+v", res.Tally)
+	}
+	if _, err := os.Stat(clips[0].Path); err != nil {
+		t.Errorf("source should be kept after failed verification: %v", err)
+	}
+}
+
+func TestS3TransferDetectsCorruptObject(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/back.mp4", 500)
+	writeClip(t, root, "TeslaCam/SentryClips/ev/front.mp4", 3000)
+
+	// The server reports what was sent but stores something else
+	fake := newFakeS3("teslacam")
+	fake.corrupt = true
+	a := newTestS3(t, fake)
+
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	res, err := a.Transfer(context.Background(), clips, nil)
+	if err == nil {
+		t.Fatal("expected verification error")
+	}
+	if res.Clips != 0 || res.Failed != 2 {
+		t.Errorf("expected 2 failed clips, got %+v", res.Tally)
+	}
+	for _, c := range clips {
+		if _, err := os.Stat(c.Path); err != nil {
+			t.Errorf("source should be kept after failed verification: %v", err)
+		}
+	}
+}
+
+func TestS3Pruner(t *testing.T) {
+	fake := newFakeS3("teslacam")
+	fake.objects["car/TeslaCam/SentryClips/2024-05-01_18-22-10/front.mp4"] = make([]byte, 300)
+	fake.objects["car/TeslaCam/SentryClips/2024-05-01_18-22-10/event.json"] = make([]byte, 20)
+	fake.objects["other/TeslaCam/SentryClips/x.mp4"] = make([]byte, 1)
+	a := newTestS3(t, fake)
+	ctx := context.Background()
+
+	files, err := a.ListStored(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files under the prefix, got %+v", files)
+	}
+	for _, f := range files {
+		if !strings.HasPrefix(f.Path, "TeslaCam/SentryClips/") || f.ModTime.IsZero() {
+			t.Errorf("unexpected stored file %+v", f)
+		}
+	}
+
+	events := storedEvents(files)
+	if len(events) != 1 || events[0].Bytes != 320 {
+		t.Fatalf("expected one 320 byte event, got %+v", events)
+	}
+	if err := a.RemoveStored(ctx, events[0].dir, events[0].files); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 1 {
+		t.Errorf("expected only the object outside the prefix left, got %d", len(fake.objects))
+	}
+}
+
+func TestS3Validate(t *testing.T) {
+	cfg := &config.Config{Archive: config.Archive{Method: "s3"}}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error with no endpoint/bucket")
+	}
+	cfg.S3 = config.S3{Endpoint: "ftp://host", Bucket: "b", AccessKey: "a", SecretKey: "s"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for non-http endpoint")
+	}
+	cfg.S3.Endpoint = "https://s3.example.com"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Example request from the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	now, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	signV4(req, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam", emptySHA256, now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	if got := awsURIEncode("/a b/c+d~", false); got != "/a%20b/c%2Bd~" {
+		t.Errorf("got %s", got)
+	}
+	if got := awsURIEncode("a/b", true); got != "a%2Fb" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// window is a parsed config.ArchiveWindow. Times are minutes after midnight.
+type window struct {
+	days       map[time.Weekday]bool // empty means every day
+	start, end int
+}
+
+func parseWindows(ws []config.ArchiveWindow) ([]window, error) {
+	var out []window
+	for i, w := range ws {
+		pw := window{days: map[time.Weekday]bool{}}
+		for _, d := range w.Days {
+			wd, ok := weekdays[strings.ToLower(d)[:min(3, len(d))]]
+			if !ok {
+				return nil, fmt.Errorf("archive window %d: unknown day %q", i+1, d)
+			}
+			pw.days[wd] = true
+		}
+		var err error
+		if pw.start, err = parseClock(w.Start); err != nil {
+			return nil, fmt.Errorf("archive window %d: start: %w", i+1, err)
+		}
+		if pw.end, err = parseClock(w.End); err != nil {
+			return nil, fmt.Errorf("archive window %d: end: %w", i+1, err)
+		}
+		out = append(out, pw)
+	}
+	return out, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// span returns when w is open if it starts on the given day. An end at or
+// before the start means the window runs past midnight.
+func (w window) span(day time.Time) (time.Time, time.Time, bool) {
+	if len(w.days) > 0 && !w.days[day.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	at := func(day time.Time, minutes int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+	}
+	endDay := day
+	if w.end <= w.start {
+		endDay = day.AddDate(0, 0, 1)
+	}
+	return at(day, w.start), at(endDay, w.end), true
+}
+
+// nextWindow returns when archiving is next allowed at or after now, and
+// whether it is allowed at all. With no windows it is always allowed.
+func nextWindow(ws []window, now time.Time) (time.Time, bool) {
+	if len(ws) == 0 {
+		return now, true
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var next time.Time
+	// Yesterday's window may still be open past midnight
+	for d := -1; d <= 7; d++ {
+		day := today.AddDate(0, 0, d)
+		for _, w := range ws {
+			start, end, ok := w.span(day)
+			if !ok || !end.After(now) {
+				continue
+			}
+			if !start.After(now) {
+				return now, true
+			}
+			if next.IsZero() || start.Before(next) {
+				next = start
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// InWindow reports whether the configured archive windows allow archiving
+// at t. With no windows configured archiving is always allowed.
+func InWindow(t time.Time) bool {
+	next, ok := NextWindow(t)
+	return ok && !next.After(t)
+}
+
+// NextWindow returns t if archiving is allowed now, otherwise when the next
+// archive window opens. ok is false if no window ever opens.
+func NextWindow(t time.Time) (next time.Time, ok bool) {
+	cfg := config.Get()
+	if cfg == nil {
+		return t, true
+	}
+	ws, err := parseWindows(cfg.Archive.Windows)
+	if err != nil {
+		return t, true // invalid windows are reported on save; don't block archiving
+	}
+	return nextWindow(ws, t)
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func TestNextWindow(t *testing.T) {
+	ws, err := parseWindows([]config.ArchiveWindow{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "22:00", End: "06:00"},
+		{Days: []string{"sat", "sun"}, Start: "09:00", End: "17:00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local) // 2024-05-06 is a Monday
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"monday evening waits", at(6, 19, 0), at(6, 22, 0)},
+		{"monday night open", at(6, 23, 30), at(6, 23, 30)},
+		{"past midnight still open", at(7, 5, 59), at(7, 5, 59)},
+		{"window just closed", at(7, 6, 0), at(7, 22, 0)},
+		{"friday night runs into saturday", at(11, 2, 0), at(11, 2, 0)},
+		{"saturday afternoon open", at(11, 12, 0), at(11, 12, 0)},
+		{"sunday evening waits for monday", at(12, 18, 0), at(13, 22, 0)},
+	}
+	for _, tt := range tests {
+		got, ok := nextWindow(ws, tt.now)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: got %v (ok=%v), want %v", tt.name, got, ok, tt.want)
+		}
+	}
+
+	if got, ok := nextWindow(nil, at(6, 19, 0)); !ok || !got.Equal(at(6, 19, 0)) {
+		t.Errorf("no windows should always allow archiving, got %v", got)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(10000)
+	ctx := context.Background()
+	start := time.Now()
+	l.wait(ctx, 10000) // one second of burst is free
+	l.wait(ctx, 2000)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected ~200ms delay, got %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.wait(cancelled, 100000); err == nil {
+		t.Error("expected wait to stop on cancel")
+	}
+	if newRateLimiter(0) != nil {
+		t.Error("expected no limiter when unlimited")
+	}
+}
@@ -0,0 +1,329 @@
+package archive
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func init() {
+	Register("sftp", newSFTP)
+}
+
+const (
+	SFTPKeyFile    = "/mutable/teslausb/sftp_key"
+	sftpKnownHosts = "/mutable/teslausb/sftp_known_hosts"
+	sftpPartialExt = ".partial"
+)
+
+type sftpArchiver struct {
+	server   string
+	port     string
+	username string
+	path     string
+	hostKey  string
+
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+func newSFTP(cfg *config.Config) (Archiver, error) {
+	c := cfg.SFTP
+	if c.Server == "" || c.Username == "" || c.Path == "" {
+		return nil, fmt.Errorf("sftp: server, username and path are required")
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		return nil, fmt.Errorf("sftp: path must be absolute, got %q", c.Path)
+	}
+	if c.HostKey != "" {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.HostKey)); err != nil {
+			return nil, fmt.Errorf("sftp: invalid host_key: %w", err)
+		}
+	}
+	port := 22
+	if c.Port != 0 {
+		port = c.Port
+	}
+	return &sftpArchiver{
+		server:   c.Server,
+		port:     strconv.Itoa(port),
+		username: c.Username,
+		path:     path.Clean(c.Path),
+		hostKey:  c.HostKey,
+	}, nil
+}
+
+func (a *sftpArchiver) Name() string { return "sftp" }
+
+func (a *sftpArchiver) Describe() string {
+	return fmt.Sprintf("sftp://%s@%s:%s%s", a.username, a.server, a.port, a.path)
+}
+
+func (a *sftpArchiver) Reachable() bool {
+	return tcpReachable(a.server, a.port)
+}
+
+// Prepare opens the SSH connection and SFTP session used by Transfer.
+func (a *sftpArchiver) Prepare(ctx context.Context) error {
+	signer, err := loadSFTPKey()
+	if err != nil {
+		return err
+	}
+	hostKeyCallback, err := a.hostKeyCallback()
+	if err != nil {
+		return err
+	}
+	sshCfg := &ssh.ClientConfig{
+		User:            a.username,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}
+
+	addr := net.JoinHostPort(a.server, a.port)
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("sftp: dial %s: %w", addr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(netConn, addr, sshCfg)
+	if err != nil {
+		netConn.Close()
+		return fmt.Errorf("sftp: ssh handshake with %s: %w", addr, err)
+	}
+	a.conn = ssh.NewClient(c, chans, reqs)
+	a.client, err = sftp.NewClient(a.conn)
+	if err != nil {
+		a.conn.Close()
+		a.conn = nil
+		return fmt.Errorf("sftp: start session: %w", err)
+	}
+	if err := a.client.MkdirAll(a.path); err != nil {
+		a.Teardown()
+		return fmt.Errorf("sftp: create %s: %w", a.path, err)
+	}
+	log.Printf("SFTP connected: %s", a.Describe())
+	return nil
+}
+
+func (a *sftpArchiver) Transfer(ctx context.Context, clips []Clip, t *Tracker) (Result, error) {
+	if a.client == nil {
+		var res Result
+		res.skipAll(clips)
+		return res, fmt.Errorf("sftp: not connected")
+	}
+	return transferEach(ctx, clips, t, a.put)
+}
+
+func (a *sftpArchiver) Teardown() {
+	if a.client != nil {
+		a.client.Close()
+		a.client = nil
+	}
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn = nil
+		log.Println("SFTP disconnected")
+	}
+}
+
+func (a *sftpArchiver) ListStored(ctx context.Context) ([]StoredFile, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("sftp: not connected")
+	}
+	var files []StoredFile
+	walker := a.client.Walk(a.path)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			return nil, err
+		}
+		info := walker.Stat()
+		// An interrupted upload isn't archived yet; the next run resumes it
+		if info.IsDir() || strings.HasSuffix(info.Name(), sftpPartialExt) {
+			continue
+		}
+		rel := strings.TrimPrefix(walker.Path(), a.path+"/")
+		files = append(files, StoredFile{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+func (a *sftpArchiver) RemoveStored(ctx context.Context, dir string, files []string) error {
+	if a.client == nil {
+		return fmt.Errorf("sftp: not connected")
+	}
+	for _, f := range files {
+		if err := a.client.Remove(path.Join(a.path, f)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", f, err)
+		}
+	}
+	for _, d := range emptiedDirs(dir, files) {
+		if a.client.RemoveDirectory(path.Join(a.path, d)) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// put uploads a clip to a ".partial" file, resuming from whatever a previous
+// attempt left behind, then renames it into place once the size matches.
+func (a *sftpArchiver) put(ctx context.Context, c Clip, t *Tracker) error {
+	final := path.Join(a.path, c.Dest)
+	partial := final + sftpPartialExt
+
+	// A previous run may have renamed the clip but lost power before
+	// removing the source
+	if info, err := a.client.Stat(final); err == nil && info.Size() == c.Size {
+		return nil
+	}
+	if err := a.client.MkdirAll(path.Dir(final)); err != nil {
+		return fmt.Errorf("mkdir %s: %w", path.Dir(final), err)
+	}
+
+	src, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := a.client.OpenFile(partial, os.O_WRONLY|os.O_CREATE)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", partial, err)
+	}
+	offset := int64(0)
+	if info, err := dst.Stat(); err == nil && info.Size() <= c.Size {
+		offset = info.Size()
+	} else if err := dst.Truncate(0); err != nil {
+		dst.Close()
+		return fmt.Errorf("truncate %s: %w", partial, err)
+	}
+	if offset > 0 {
+		log.Printf("sftp: resuming %s at %d bytes", c.Key(), offset)
+		t.Add(c, offset)
+	}
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		dst.Close()
+		return err
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		dst.Close()
+		return err
+	}
+	if _, err := io.Copy(dst, t.Reader(c, throttle(ctx, src))); err != nil {
+		dst.Close()
+		return fmt.Errorf("write %s: %w", partial, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", partial, err)
+	}
+
+	info, err := a.client.Stat(partial)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", partial, err)
+	}
+	if info.Size() != c.Size {
+		return fmt.Errorf("verify %s: size %d, expected %d", partial, info.Size(), c.Size)
+	}
+	a.client.Chtimes(partial, c.ModTime, c.ModTime)
+
+	if _, ok := a.client.HasExtension("posix-rename@openssh.com"); ok {
+		err = a.client.PosixRename(partial, final)
+	} else {
+		a.client.Remove(final)
+		err = a.client.Rename(partial, final)
+	}
+	if err != nil {
+		return fmt.Errorf("rename %s: %w", partial, err)
+	}
+	return nil
+}
+
+// hostKeyCallback pins the configured host key, or trusts the first key
+// seen for the server and rejects any later change.
+func (a *sftpArchiver) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if a.hostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(a.hostKey))
+		if err != nil {
+			return nil, fmt.Errorf("sftp: invalid host_key: %w", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	if f, err := os.OpenFile(sftpKnownHosts, os.O_CREATE|os.O_RDONLY, 0600); err == nil {
+		f.Close()
+	}
+	known, err := knownhosts.New(sftpKnownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: known hosts: %w", err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			log.Printf("sftp: trusting new host key for %s (%s)", hostname, ssh.FingerprintSHA256(key))
+			f, ferr := os.OpenFile(sftpKnownHosts, os.O_APPEND|os.O_WRONLY, 0600)
+			if ferr != nil {
+				return ferr
+			}
+			defer f.Close()
+			_, ferr = fmt.Fprintln(f, knownhosts.Line([]string{hostname}, key))
+			return ferr
+		}
+		return err
+	}, nil
+}
+
+// SFTPPublicKey returns the daemon's SFTP public key in authorized_keys
+// format, generating the key pair on first use.
+func SFTPPublicKey() (string, error) {
+	signer, err := loadSFTPKey()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " teslausb", nil
+}
+
+func loadSFTPKey() (ssh.Signer, error) {
+	data, err := os.ReadFile(SFTPKeyFile)
+	if os.IsNotExist(err) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "teslausb")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.WriteFile(SFTPKeyFile, data, 0600); err != nil {
+			return nil, fmt.Errorf("sftp: write key: %w", err)
+		}
+		log.Printf("sftp: generated key %s", SFTPKeyFile)
+	} else if err != nil {
+		return nil, fmt.Errorf("sftp: read key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: parse key %s: %w", SFTPKeyFile, err)
+	}
+	return signer, nil
+}
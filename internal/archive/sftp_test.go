@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// newTestSFTP connects an sftpArchiver to an in-process SFTP server that
+// serves the local filesystem, storing clips under a temp dir.
+func newTestSFTP(t *testing.T) (*sftpArchiver, string) {
+	t.Helper()
+	c1, c2 := net.Pipe()
+	server, err := sftp.NewServer(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(c2, c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	remote := t.TempDir()
+	return &sftpArchiver{path: remote, client: client}, remote
+}
+
+func TestSFTPTransferResumesPartial(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/front.mp4", 4096)
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	src, _ := os.ReadFile(clips[0].Path)
+
+	a, remote := newTestSFTP(t)
+	final := filepath.Join(remote, "TeslaCam/SentryClips/ev/front.mp4")
+	os.MkdirAll(filepath.Dir(final), 0755)
+	os.WriteFile(final+sftpPartialExt, src[:1000], 0644)
+
+	res, err := a.Transfer(context.Background(), clips, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Clips != 1 || res.Bytes != 4096 {
+		t.Errorf("expected 1 clip/4096 bytes, got %d/%d", res.Clips, res.Bytes)
+	}
+	got, err := os.ReadFile(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Error("resumed upload does not match source")
+	}
+	if _, err := os.Stat(final + sftpPartialExt); !os.IsNotExist(err) {
+		t.Error("partial file should be renamed away")
+	}
+	if info, _ := os.Stat(final); !info.ModTime().Equal(clips[0].ModTime.Truncate(1e9)) {
+		t.Errorf("expected mtime %v, got %v", clips[0].ModTime, info.ModTime())
+	}
+	if _, err := os.Stat(clips[0].Path); !os.IsNotExist(err) {
+		t.Error("source should be removed after upload")
+	}
+}
+
+func TestSFTPValidate(t *testing.T) {
+	cfg := &config.Config{Archive: config.Archive{Method: "sftp"}}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error with no server")
+	}
+	cfg.SFTP = config.SFTP{Server: "nas", Username: "pi", Path: "TeslaCam"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for relative path")
+	}
+	cfg.SFTP.Path = "/srv/teslacam"
+	cfg.SFTP.HostKey = "not a key"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for invalid host key")
+	}
+	cfg.SFTP.HostKey = ""
+	if err := Validate(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSFTPPruner(t *testing.T) {
+	a, remote := newTestSFTP(t)
+	writeClip(t, remote, "TeslaCam/RecentClips/2024-05-01_18-22-10-front.mp4", 40)
+	writeClip(t, remote, "TeslaCam/SavedClips/2024-05-02_18-22-10/front.mp4", 10)
+	writeClip(t, remote, "TeslaCam/SavedClips/2024-05-02_18-22-10/back.mp4"+sftpPartialExt, 5)
+	ctx := context.Background()
+
+	files, err := a.ListStored(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the partial upload left out, got %+v", files)
+	}
+	events := storedEvents(files)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	for _, ev := range events {
+		if ev.Category != "RecentClips" {
+			continue
+		}
+		if err := a.RemoveStored(ctx, ev.dir, ev.files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(remote, "TeslaCam/RecentClips")); !os.IsNotExist(err) {
+		t.Error("expected emptied RecentClips folder removed")
+	}
+	if _, err := os.Stat(filepath.Join(remote, "TeslaCam/SavedClips/2024-05-02_18-22-10/front.mp4")); err != nil {
+		t.Errorf("other events must be kept: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package archive
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/teslausb-go/teslausb/internal/disk"
+)
+
+// Source is the cam disk an archive run reads clips from.
+type Source struct {
+	Root string
+	// Snapshot marks a read-only snapshot of the image the car is using.
+	// Sources are left in place and the run's journal lists what the live
+	// image can drop afterwards; see JournalState.RemoveConfirmed.
+	Snapshot bool
+}
+
+// LiveSource is the cam disk itself, mounted while the gadget is disabled.
+var LiveSource = Source{Root: disk.MountPoint}
+
+// keepsSources reports whether the run reads from a snapshot, so archived
+// clips must not be removed from the source.
+func (t *Tracker) keepsSources() bool {
+	return t != nil && t.snapshot
+}
+
+// removeSource deletes a clip once it is safely archived, unless the run
+// reads from a snapshot or the clip is kept on the cam disk.
+func (t *Tracker) removeSource(c Clip) {
+	if t.keepsSources() || c.Keep {
+		return
+	}
+	if err := os.Remove(c.Path); err != nil {
+		log.Printf("remove source %s: %v", c.Key(), err)
+	}
+}
+
+func removeClip(c Clip) error {
+	return os.Remove(c.Path)
+}
+
+// CleanClipDirs removes event folders left empty under the cam disk at root.
+func CleanClipDirs(root string) {
+	for _, cat := range clipCategories {
+		cleanEmptyDirs(filepath.Join(root, "TeslaCam", cat))
+	}
+}
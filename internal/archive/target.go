@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// Target is a named archive destination.
+type Target struct {
+	Name string
+	Archiver
+}
+
+// Targets builds the archive destinations of cfg in priority order. Without
+// cfg.Targets, the top-level backend settings form a single target named
+// after its method.
+func Targets(cfg *config.Config) ([]Target, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no config")
+	}
+	if len(cfg.Targets) == 0 {
+		a, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []Target{{Name: a.Name(), Archiver: a}}, nil
+	}
+	seen := map[string]bool{}
+	targets := make([]Target, 0, len(cfg.Targets))
+	for i, t := range cfg.Targets {
+		if t.Name == "" {
+			return nil, fmt.Errorf("archive target %d: name is required", i+1)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("archive target %q listed twice", t.Name)
+		}
+		seen[t.Name] = true
+		a, err := New(cfg.ForTarget(t))
+		if err != nil {
+			return nil, fmt.Errorf("archive target %q: %w", t.Name, err)
+		}
+		targets = append(targets, Target{Name: t.Name, Archiver: a})
+	}
+	return targets, nil
+}
+
+// firstReachable returns the highest-priority target that is reachable.
+func firstReachable(targets []Target) (Target, bool) {
+	for _, t := range targets {
+		if t.Reachable() {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
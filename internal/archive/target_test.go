@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+type stubArchiver struct {
+	mountedShare
+	name      string
+	reachable bool
+}
+
+func (s stubArchiver) Name() string                      { return s.name }
+func (s stubArchiver) Describe() string                  { return s.name }
+func (s stubArchiver) Reachable() bool                   { return s.reachable }
+func (s stubArchiver) Prepare(ctx context.Context) error { return nil }
+
+func TestTargetsOrder(t *testing.T) {
+	cfg := &config.Config{
+		NFS: config.NFS{Server: "ignored", Share: "/ignored"},
+		Targets: []config.Target{
+			{Name: "home", Method: "nfs", NFS: config.NFS{Server: "nas", Share: "/data"}},
+			{Name: "family", Method: "cifs", CIFS: config.CIFS{Server: "nas2", Share: "TeslaCam"}},
+		},
+	}
+	targets, err := Targets(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Name != "home" || targets[1].Name != "family" {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if got := targets[0].Describe(); got != "nas:/data" {
+		t.Errorf("home describes as %q, want its own settings", got)
+	}
+	if got := targets[1].Archiver.Name(); got != "cifs" {
+		t.Errorf("family backend = %q, want cifs", got)
+	}
+
+	cfg.Targets = append(cfg.Targets, config.Target{Name: "home", Method: "nfs", NFS: config.NFS{Server: "x", Share: "/y"}})
+	if _, err := Targets(cfg); err == nil {
+		t.Error("expected error for duplicate target name")
+	}
+}
+
+func TestTargetsLegacy(t *testing.T) {
+	targets, err := Targets(&config.Config{NFS: config.NFS{Server: "nas", Share: "/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Name != "nfs" || targets[0].Describe() != "nas:/data" {
+		t.Errorf("targets = %+v", targets)
+	}
+}
+
+func TestFirstReachable(t *testing.T) {
+	targets := []Target{
+		{Name: "home", Archiver: stubArchiver{name: "nfs"}},
+		{Name: "family", Archiver: stubArchiver{name: "nfs", reachable: true}},
+		{Name: "cloud", Archiver: stubArchiver{name: "s3", reachable: true}},
+	}
+	if got, ok := firstReachable(targets); !ok || got.Name != "family" {
+		t.Errorf("firstReachable = %q, %v; want family", got.Name, ok)
+	}
+	if _, ok := firstReachable(targets[:1]); ok {
+		t.Error("expected no reachable target")
+	}
+}
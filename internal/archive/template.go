@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// DefaultPathTemplate reproduces the cam disk layout on the archive.
+const DefaultPathTemplate = "TeslaCam/{category}/{event}"
+
+// templateFields are the placeholders a path template may use.
+var templateFields = map[string]bool{
+	"category": true, // SavedClips, SentryClips or RecentClips
+	"year":     true,
+	"month":    true,
+	"day":      true,
+	"event":    true, // event folder name, e.g. 2024-05-01_18-22-10
+	"vin":      true, // keep_awake.vin
+	"hostname": true,
+}
+
+// ValidateTemplate checks that tmpl only uses known placeholders, keeps
+// event folders apart and stays inside the archive root.
+func ValidateTemplate(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+	if strings.HasPrefix(tmpl, "/") {
+		return fmt.Errorf("path template must be relative, got %q", tmpl)
+	}
+	names, err := templateNames(tmpl)
+	if err != nil {
+		return err
+	}
+	hasEvent := false
+	for _, name := range names {
+		if !templateFields[name] {
+			return fmt.Errorf("path template: unknown placeholder {%s}", name)
+		}
+		hasEvent = hasEvent || name == "event"
+	}
+	if !hasEvent {
+		return fmt.Errorf("path template must include {event} so event folders don't overwrite each other")
+	}
+	for _, seg := range strings.Split(tmpl, "/") {
+		if seg == ".." {
+			return fmt.Errorf("path template must not contain \"..\"")
+		}
+	}
+	return nil
+}
+
+// templateNames returns the placeholder names in tmpl in order.
+func templateNames(tmpl string) ([]string, error) {
+	var names []string
+	rest := tmpl
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			return names, nil
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("path template: unexpected '}' in %q", tmpl)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] != '}' {
+			return nil, fmt.Errorf("path template: unclosed '{' in %q", tmpl)
+		}
+		names = append(names, rest[open+1:open+1+end])
+		rest = rest[open+1+end+1:]
+	}
+}
+
+func expandTemplate(tmpl string, vars map[string]string) string {
+	for name, v := range vars {
+		tmpl = strings.ReplaceAll(tmpl, "{"+name+"}", v)
+	}
+	// Empty values, e.g. an unset {vin}, drop out of the path
+	return strings.TrimPrefix(path.Clean("/"+tmpl), "/")
+}
+
+// applyTemplate sets each clip's Dest from tmpl. The event folder is
+// replaced by the expanded template and anything below it is kept, so with
+// DefaultPathTemplate Dest equals Key. Dates come from event.json, then the
+// event folder name, then the clip's mtime.
+func applyTemplate(clips []Clip, events map[string]Event, tmpl string, cfg *config.Config) {
+	if tmpl == "" {
+		tmpl = DefaultPathTemplate
+	}
+	hostname, _ := os.Hostname()
+	vin := ""
+	if cfg != nil {
+		vin = cfg.KeepAwake.VIN
+	}
+
+	for i := range clips {
+		c := &clips[i]
+		event, rest, ok := strings.Cut(c.RelPath, "/")
+		if !ok {
+			event, rest = "", c.RelPath
+		}
+		ts := c.ModTime
+		if ev, ok := events[c.event()]; ok && !ev.Timestamp.IsZero() {
+			ts = ev.Timestamp
+		} else if t, err := time.ParseInLocation(eventFolderLayout, event, time.Local); err == nil {
+			ts = t
+		}
+		dir := expandTemplate(tmpl, map[string]string{
+			"category": c.Category(),
+			"year":     ts.Format("2006"),
+			"month":    ts.Format("01"),
+			"day":      ts.Format("02"),
+			"event":    event,
+			"vin":      vin,
+			"hostname": hostname,
+		})
+		c.Dest = path.Join(dir, rest)
+	}
+}
@@ -0,0 +1,107 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{"", false},
+		{DefaultPathTemplate, false},
+		{"{vin}/{category}/{year}/{month}/{day}/{event}", false},
+		{"{hostname}/{year}-{month}/{event}", false},
+		{"{category}/{year}", true},         // events would overwrite each other
+		{"{category}/{week}/{event}", true}, // unknown placeholder
+		{"{category/{event}", true},
+		{"{event}}", true},
+		{"/srv/{event}", true},
+		{"../{event}", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateTemplate(tt.tmpl); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTemplate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	clips := []Clip{
+		{Dir: "TeslaCam/SentryClips", RelPath: "2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4"},
+		{Dir: "TeslaCam/SavedClips", RelPath: "2024-06-02_09-00-00/event.json"},
+		{Dir: "TeslaCam/RecentClips", RelPath: "2024-07-03_10-00-00-back.mp4", ModTime: time.Date(2024, 7, 3, 10, 0, 0, 0, time.Local)},
+	}
+	events := map[string]Event{
+		// event.json wins over the folder name
+		"TeslaCam/SavedClips/2024-06-02_09-00-00": {Timestamp: time.Date(2024, 6, 1, 23, 59, 0, 0, time.Local)},
+	}
+	cfg := &config.Config{KeepAwake: config.KeepAwake{VIN: "5YJ3E1EA1KF000001"}}
+
+	applyTemplate(clips, events, "{vin}/{category}/{year}/{month}/{day}/{event}", cfg)
+	want := []string{
+		"5YJ3E1EA1KF000001/SentryClips/2024/05/01/2024-05-01_18-22-10/2024-05-01_18-12-10-front.mp4",
+		"5YJ3E1EA1KF000001/SavedClips/2024/06/01/2024-06-02_09-00-00/event.json",
+		"5YJ3E1EA1KF000001/RecentClips/2024/07/03/2024-07-03_10-00-00-back.mp4",
+	}
+	for i, c := range clips {
+		if c.Dest != want[i] {
+			t.Errorf("got  %s\nwant %s", c.Dest, want[i])
+		}
+	}
+
+	// The default layout matches the cam disk, even with no VIN set
+	applyTemplate(clips, events, "", &config.Config{})
+	for _, c := range clips {
+		if c.Dest != c.Key() {
+			t.Errorf("default template: got %s, want %s", c.Dest, c.Key())
+		}
+	}
+}
+
+func TestRsyncGroups(t *testing.T) {
+	clips := []Clip{
+		{Dir: "TeslaCam/SentryClips", RelPath: "b/front.mp4", Path: "/mnt/snapshots/archive/TeslaCam/SentryClips/b/front.mp4", Dest: "TeslaCam/SentryClips/b/front.mp4"},
+		{Dir: "TeslaCam/SentryClips", RelPath: "b/back.mp4", Path: "/mnt/snapshots/archive/TeslaCam/SentryClips/b/back.mp4", Dest: "TeslaCam/SentryClips/b/back.mp4"},
+		{Dir: "TeslaCam/SentryClips", RelPath: "a/front.mp4", Path: "/mnt/snapshots/archive/TeslaCam/SentryClips/a/front.mp4", Dest: "TeslaCam/SentryClips/a/front.mp4"},
+	}
+	groups := rsyncGroups(clips)
+	if len(groups) != 2 {
+		t.Fatalf("expected one rsync per event, got %d", len(groups))
+	}
+	if g := groups[0]; g.root != "/mnt/snapshots/archive/" || g.src != "TeslaCam/SentryClips" || g.dst != "TeslaCam/SentryClips" {
+		t.Errorf("unexpected group %+v", g)
+	}
+	// Groups and files keep the prioritized order
+	if got := strings.Join(append(groups[0].files, groups[1].files...), " "); got != "b/front.mp4 b/back.mp4 a/front.mp4" {
+		t.Errorf("unexpected order %s", got)
+	}
+
+	clips[0].Dest = "SentryClips/2024/06/b/front.mp4"
+	clips[1].Dest = "SentryClips/2024/06/b/back.mp4"
+	clips[2].Dest = "SentryClips/2024/05/a/front.mp4"
+	groups = rsyncGroups(clips)
+	if len(groups) != 2 {
+		t.Fatalf("expected one rsync per event, got %d", len(groups))
+	}
+	if g := groups[1]; g.src != "TeslaCam/SentryClips" || g.dst != "SentryClips/2024/05" || g.byFile()["a/front.mp4"].RelPath != "a/front.mp4" {
+		t.Errorf("unexpected group %+v", g)
+	}
+
+	// Large events are split so pause and cancel don't wait for all of it
+	clips = nil
+	for i := range rsyncBatchFiles + 1 {
+		rel := fmt.Sprintf("a/%d.mp4", i)
+		clips = append(clips, Clip{Dir: "TeslaCam/SentryClips", RelPath: rel, Path: "/mnt/cam/TeslaCam/SentryClips/" + rel, Dest: "TeslaCam/SentryClips/" + rel})
+	}
+	groups = rsyncGroups(clips)
+	if len(groups) != 2 || len(groups[0].clips) != rsyncBatchFiles || groups[1].files[0] != clips[rsyncBatchFiles].RelPath {
+		t.Errorf("expected batches of %d, got %d groups", rsyncBatchFiles, len(groups))
+	}
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by every transfer in a run, so the
+// cap holds however many clips are in flight.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(bytesPerSec), tokens: float64(bytesPerSec), last: time.Now()}
+}
+
+// wait blocks until n bytes may be sent.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	// Allow at most one second of burst
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(0)
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var (
+	limiterMu sync.Mutex
+	limiter   *rateLimiter
+	limitKB   int
+)
+
+// setBandwidth caps transfers started after it is called; kbps <= 0 removes
+// the cap. Like rsync --bwlimit, the unit is KiB per second.
+func setBandwidth(kbps int) {
+	limiterMu.Lock()
+	limiter = newRateLimiter(int64(kbps) * 1024)
+	limitKB = max(kbps, 0)
+	limiterMu.Unlock()
+}
+
+// bandwidthKB returns the current cap in KiB/s for rsync --bwlimit, or 0.
+func bandwidthKB() int {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+	return limitKB
+}
+
+// throttle wraps r so reads honour the bandwidth cap and stop once ctx is
+// cancelled. Engines that don't use rsync read their source through it.
+func throttle(ctx context.Context, r io.Reader) io.Reader {
+	limiterMu.Lock()
+	l := limiter
+	limiterMu.Unlock()
+	if l == nil {
+		return contextReader{ctx, r}
+	}
+	return &throttledReader{ctx: ctx, l: l, r: r}
+}
+
+type throttledReader struct {
+	ctx context.Context
+	l   *rateLimiter
+	r   io.Reader
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	// Small reads keep the rate smooth
+	if len(p) > 32*1024 {
+		p = p[:32*1024]
+	}
+	if err := tr.l.wait(tr.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	return tr.r.Read(p)
+}
+
+// contextReader stops a copy once ctx is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/sys/unix"
+)
+
+// verifyAttempts is how many times a clip is copied before a checksum
+// mismatch is reported as a failure.
+const verifyAttempts = 3
+
+// errChecksumMismatch is a copy whose content differs from its source.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// VerifyError lists clips whose archived copy still did not match the
+// source after all retries. Their sources are kept on the cam disk.
+type VerifyError struct {
+	Files []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%d clips failed verification: %s", len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// newHasher returns a constructor for the archive.verify algorithm.
+func newHasher(algo string) (func() hash.Hash, error) {
+	switch algo {
+	case "sha256":
+		return sha256.New, nil
+	case "xxhash":
+		return func() hash.Hash { return xxhash.New() }, nil
+	}
+	return nil, fmt.Errorf("unknown verify algorithm %q (available: sha256, xxhash)", algo)
+}
+
+// rsyncVerified copies clips into dstRoot with rsync, leaving the sources in
+// place, then reads each copy back and compares checksums. A source is only
+// removed once its copy matches; mismatched clips are resent up to
+// verifyAttempts times.
+func rsyncVerified(ctx context.Context, dstRoot string, clips []Clip, t *Tracker, algo string) (Result, error) {
+	var res Result
+	newHash, err := newHasher(algo)
+	if err != nil {
+		res.skipAll(clips)
+		return res, err
+	}
+
+	pending := clips
+	opts := rsyncOptions{keepSource: true, verifying: true}
+
+	for attempt := 1; attempt <= verifyAttempts && len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			log.Printf("verify: resending %d clips (attempt %d of %d)", len(pending), attempt, verifyAttempts)
+			// Size and mtime already match, so rsync must not skip them.
+			// Retries aren't counted in t's progress a second time.
+			opts.ignoreTimes = true
+			t.resend(pending)
+		}
+		if _, err := rsyncClips(ctx, dstRoot, pending, t, opts); err != nil {
+			if ctx.Err() != nil {
+				res.skipAll(pending)
+				return res, ctx.Err()
+			}
+			log.Printf("verify: %v", err)
+		}
+
+		var mismatched []Clip
+		for i, c := range pending {
+			if err := verifyCopy(ctx, c, filepath.Join(dstRoot, filepath.FromSlash(c.Dest)), newHash); err != nil {
+				if ctx.Err() != nil {
+					res.skipAll(append(mismatched, pending[i:]...))
+					return res, ctx.Err()
+				}
+				log.Printf("verify %s: %v", c.Key(), err)
+				mismatched = append(mismatched, c)
+				continue
+			}
+			t.Confirm(c)
+			t.removeSource(c)
+			res.add(c, archived)
+		}
+		pending = mismatched
+	}
+
+	if len(pending) > 0 {
+		verr := &VerifyError{}
+		for _, c := range pending {
+			t.Fail(c)
+			res.add(c, failed)
+			res.FailedFiles = append(res.FailedFiles, FileError{Path: c.Key(), Error: errChecksumMismatch.Error()})
+			verr.Files = append(verr.Files, c.Key())
+		}
+		return res, verr
+	}
+	return res, nil
+}
+
+// verifyCopy checks that the archived file at dst has the same content as
+// the clip's source.
+func verifyCopy(ctx context.Context, c Clip, dst string, newHash func() hash.Hash) error {
+	want, err := hashFile(ctx, c.Path, newHash)
+	if err != nil {
+		return fmt.Errorf("read source: %w", err)
+	}
+	got, err := hashFile(ctx, dst, newHash)
+	if err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+	if !bytes.Equal(want, got) {
+		return errChecksumMismatch
+	}
+	return nil
+}
+
+func hashFile(ctx context.Context, path string, newHash func() hash.Hash) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Drop cached pages so an archive copy is read back from the server
+	// rather than from what we just wrote
+	unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+
+	h := newHash()
+	if _, err := io.Copy(h, contextReader{ctx, f}); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
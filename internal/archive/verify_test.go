@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyCopy(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "cam/TeslaCam/SentryClips/ev/front.mp4", 4096)
+	writeClip(t, root, "nas/TeslaCam/SentryClips/ev/front.mp4", 4096)
+	c := collectClips(filepath.Join(root, "cam"), []string{"TeslaCam/SentryClips"})[0]
+	dst := filepath.Join(root, "nas", c.Key())
+
+	for _, algo := range []string{"sha256", "xxhash"} {
+		newHash, err := newHasher(algo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyCopy(context.Background(), c, dst, newHash); err != nil {
+			t.Errorf("%s: identical copy rejected: %v", algo, err)
+		}
+	}
+
+	// Same size, one flipped byte
+	data, _ := os.ReadFile(dst)
+	data[2000] ^= 0xff
+	os.WriteFile(dst, data, 0644)
+	newHash, _ := newHasher("xxhash")
+	if err := verifyCopy(context.Background(), c, dst, newHash); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+	if err := verifyCopy(context.Background(), c, dst+".missing", newHash); err == nil {
+		t.Error("expected error for missing copy")
+	}
+}
+
+func TestTrackerFailCountsOnce(t *testing.T) {
+	c := Clip{Dir: "TeslaCam/SavedClips", RelPath: "ev/front.mp4", Size: 10}
+	tracker := NewTracker([]Clip{c}, nil)
+	tracker.Finish(c, errTest)
+	tracker.Fail(c)
+	if p := tracker.Snapshot(); p.FilesFailed != 1 {
+		t.Errorf("expected 1 failed file, got %d", p.FilesFailed)
+	}
+}
+
+func TestTrackerResendCountsOnce(t *testing.T) {
+	c := Clip{Dir: "TeslaCam/SavedClips", RelPath: "ev/front.mp4", Size: 10}
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewTracker([]Clip{c}, nil)
+	tracker.journal = j
+	tracker.Start(c)
+	tracker.Add(c, 10)
+	tracker.Finish(c, nil)
+
+	// A copy that failed verification is sent again
+	tracker.resend([]Clip{c})
+	tracker.Start(c)
+	tracker.Add(c, 10)
+	tracker.Finish(c, nil)
+	if p := tracker.Snapshot(); p.FilesDone != 1 || p.BytesDone != 10 {
+		t.Errorf("resend counted twice: %+v", p)
+	}
+	st, err := ReadJournal(path)
+	if err != nil || st == nil || len(st.InFlight) != 1 {
+		t.Fatalf("expected the resend journaled as in flight, got %+v %v", st, err)
+	}
+	tracker.Confirm(c)
+	if st, _ := ReadJournal(path); st == nil || len(st.Confirmed) != 1 || len(st.InFlight) != 0 {
+		t.Errorf("expected the resend confirmed, got %+v", st)
+	}
+}
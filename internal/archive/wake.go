@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+	"github.com/teslausb-go/teslausb/internal/wol"
+)
+
+const (
+	defaultWakeBroadcast = "255.255.255.255"
+	defaultWakePort      = 9
+	defaultWakeTimeout   = 180 * time.Second
+)
+
+// wakePoll is how often Wake checks whether the server is up and sends
+// another magic packet in case the last one was lost.
+var wakePoll = 10 * time.Second
+
+// ValidateWakeOnLAN checks the server's MAC address and broadcast address,
+// and that the home network is defined so the daemon knows when to wake it.
+func ValidateWakeOnLAN(w config.WakeOnLAN, home config.Home) error {
+	if w.MAC == "" {
+		return nil
+	}
+	if _, err := wol.MagicPacket(w.MAC); err != nil {
+		return fmt.Errorf("wake_on_lan.mac: %w", err)
+	}
+	if w.Broadcast != "" && net.ParseIP(w.Broadcast) == nil {
+		return fmt.Errorf("wake_on_lan.broadcast must be an IP address, got %q", w.Broadcast)
+	}
+	if w.Port < 0 || w.Port > 65535 {
+		return fmt.Errorf("wake_on_lan.port must be between 0 and 65535")
+	}
+	if w.TimeoutSeconds < 0 {
+		return fmt.Errorf("wake_on_lan.timeout_seconds must not be negative")
+	}
+	if len(home.SSIDs) == 0 && len(home.Gateways) == 0 {
+		return fmt.Errorf("wake_on_lan needs home.ssids or home.gateways")
+	}
+	return nil
+}
+
+// Wake sends magic packets to the archive server described by w and waits
+// until an archive target is reachable. Returns the target's name, or an
+// error if none came up within the timeout.
+func Wake(ctx context.Context, w config.WakeOnLAN) (string, error) {
+	broadcast, port, timeout := w.Broadcast, w.Port, time.Duration(w.TimeoutSeconds)*time.Second
+	if broadcast == "" {
+		broadcast = defaultWakeBroadcast
+	}
+	if port == 0 {
+		port = defaultWakePort
+	}
+	if timeout == 0 {
+		timeout = defaultWakeTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := wol.Send(w.MAC, broadcast, port); err != nil {
+			return "", err
+		}
+		if target, ok := IsReachable(); ok {
+			log.Printf("archive target %s is awake", target)
+			return target, nil
+		}
+		if !time.Now().Before(deadline) {
+			return "", fmt.Errorf("archive server %s not reachable %s after wake-on-lan", w.MAC, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(min(wakePoll, time.Until(deadline))):
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package archive
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func init() {
+	Register("webdav", newWebDAV)
+}
+
+type webdavArchiver struct {
+	base     *url.URL
+	username string
+	password string
+	token    string
+	client   *http.Client
+
+	// collections known to exist on the server, so each event folder is
+	// only MKCOL'd once per run; guarded by mu while workers upload
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+func newWebDAV(cfg *config.Config) (Archiver, error) {
+	c := cfg.WebDAV
+	if c.URL == "" {
+		return nil, fmt.Errorf("webdav: url is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webdav: url must be an http(s) URL, got %q", c.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return &webdavArchiver{
+		base:     u,
+		username: c.Username,
+		password: c.Password,
+		token:    c.Token,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+func (a *webdavArchiver) Name() string { return "webdav" }
+
+func (a *webdavArchiver) Describe() string {
+	return a.base.Redacted()
+}
+
+func (a *webdavArchiver) Reachable() bool {
+	port := a.base.Port()
+	if port == "" {
+		port = "443"
+		if a.base.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return tcpReachable(a.base.Hostname(), port)
+}
+
+// Prepare checks the base collection exists and the credentials are
+// accepted, creating the collection if it is missing.
+func (a *webdavArchiver) Prepare(ctx context.Context) error {
+	a.created = map[string]bool{}
+	resp, err := a.request(ctx, "PROPFIND", "", nil, -1, map[string]string{"Depth": "0"})
+	if err != nil {
+		return fmt.Errorf("webdav: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		if err := a.mkcol(ctx, ""); err != nil {
+			return fmt.Errorf("webdav: %w", err)
+		}
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("webdav: PROPFIND %s: HTTP %d", a.Describe(), resp.StatusCode)
+	}
+	a.created[""] = true
+	return nil
+}
+
+func (a *webdavArchiver) Transfer(ctx context.Context, clips []Clip, t *Tracker) (Result, error) {
+	if a.created == nil {
+		a.created = map[string]bool{"": true}
+	}
+	return transferEach(ctx, clips, t, a.put)
+}
+
+func (a *webdavArchiver) Teardown() {
+	a.created = nil
+}
+
+// put creates the clip's collection tree, uploads it, and confirms the
+// server reports the expected size.
+func (a *webdavArchiver) put(ctx context.Context, c Clip, t *Tracker) error {
+	key := c.Dest
+	if err := a.mkcolAll(ctx, path.Dir(key)); err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resp, err := a.request(ctx, http.MethodPut, key, t.Reader(c, throttle(ctx, f)), c.Size, map[string]string{
+		"X-OC-Mtime": fmt.Sprint(c.ModTime.Unix()), // Nextcloud/ownCloud preserve mtime
+	})
+	if err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("put %s: HTTP %d", key, resp.StatusCode)
+	}
+
+	resp, err = a.request(ctx, http.MethodHead, key, nil, -1, nil)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", key, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("verify %s: HTTP %d", key, resp.StatusCode)
+	}
+	if resp.ContentLength != c.Size {
+		return fmt.Errorf("verify %s: size %d, expected %d", key, resp.ContentLength, c.Size)
+	}
+	return nil
+}
+
+// davMultistatus is the part of a PROPFIND response ListStored reads.
+type davMultistatus struct {
+	Responses []struct {
+		Href string `xml:"href"`
+		Prop struct {
+			Collection   *struct{} `xml:"resourcetype>collection"`
+			Length       int64     `xml:"getcontentlength"`
+			LastModified string    `xml:"getlastmodified"`
+		} `xml:"propstat>prop"`
+	} `xml:"response"`
+}
+
+// ListStored walks the collection tree one PROPFIND (Depth: 1) at a time,
+// since many servers refuse Depth: infinity.
+func (a *webdavArchiver) ListStored(ctx context.Context) ([]StoredFile, error) {
+	var files []StoredFile
+	pending := []string{""}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		resp, err := a.request(ctx, "PROPFIND", dir, nil, -1, map[string]string{"Depth": "1"})
+		if err != nil {
+			return nil, err
+		}
+		var ms davMultistatus
+		if resp.StatusCode == http.StatusMultiStatus {
+			err = xml.NewDecoder(resp.Body).Decode(&ms)
+		} else {
+			err = fmt.Errorf("PROPFIND %s: HTTP %d", dir, resp.StatusCode)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range ms.Responses {
+			u, err := url.Parse(r.Href)
+			if err != nil {
+				continue
+			}
+			rel := strings.Trim(strings.TrimPrefix(u.Path, a.base.Path), "/")
+			if rel == dir {
+				continue
+			}
+			if r.Prop.Collection != nil {
+				pending = append(pending, rel)
+				continue
+			}
+			modTime, _ := http.ParseTime(r.Prop.LastModified)
+			files = append(files, StoredFile{Path: rel, Size: r.Prop.Length, ModTime: modTime})
+		}
+	}
+	return files, nil
+}
+
+// RemoveStored deletes files and then the event folder. DELETE on a
+// collection is recursive, so emptied parents are left alone.
+func (a *webdavArchiver) RemoveStored(ctx context.Context, dir string, files []string) error {
+	keys := slices.Clone(files)
+	if dir != "" {
+		keys = append(keys, dir)
+	}
+	for _, key := range keys {
+		resp, err := a.request(ctx, http.MethodDelete, key, nil, -1, nil)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("delete %s: HTTP %d", key, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// mkcolAll creates dir and each missing parent, e.g. TeslaCam,
+// TeslaCam/SavedClips, TeslaCam/SavedClips/<event>.
+func (a *webdavArchiver) mkcolAll(ctx context.Context, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	a.mu.Lock()
+	done := a.created[dir]
+	a.mu.Unlock()
+	if done {
+		return nil
+	}
+	if err := a.mkcolAll(ctx, path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := a.mkcol(ctx, dir); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.created[dir] = true
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *webdavArchiver) mkcol(ctx context.Context, dir string) error {
+	resp, err := a.request(ctx, "MKCOL", dir, nil, -1, nil)
+	if err != nil {
+		return fmt.Errorf("mkcol %s: %w", dir, err)
+	}
+	resp.Body.Close()
+	// 405 means the collection already exists
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("mkcol %s: HTTP %d", dir, resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *webdavArchiver) request(ctx context.Context, method, key string, body io.Reader, size int64, header map[string]string) (*http.Response, error) {
+	u := *a.base
+	if key != "" {
+		u.Path = a.base.Path + "/" + key
+	}
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	} else if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: authentication failed (HTTP %d)", method, key, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func newTestWebDAV(t *testing.T, cfg config.WebDAV) (Archiver, string) {
+	t.Helper()
+	remote := t.TempDir()
+	dav := &webdav.Handler{FileSystem: webdav.Dir(remote), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		bearer := r.Header.Get("Authorization") == "Bearer tok"
+		if !bearer && (!ok || user != "alice" || pass != "pw") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		dav.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL + "/TeslaUSB/"
+	a, err := newWebDAV(&config.Config{WebDAV: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, remote
+}
+
+func TestWebDAVTransfer(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4", 2048)
+	writeClip(t, root, "TeslaCam/SavedClips/2024-05-01_18-22-10/thumb.png", 64)
+
+	a, remote := newTestWebDAV(t, config.WebDAV{Username: "alice", Password: "pw"})
+	ctx := context.Background()
+	if err := a.Prepare(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Teardown()
+
+	clips := collectClips(root, []string{"TeslaCam/SavedClips"})
+	res, err := a.Transfer(ctx, clips, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Clips != 2 || res.Bytes != 2112 {
+		t.Errorf("expected 2 clips/2112 bytes, got %d/%d", res.Clips, res.Bytes)
+	}
+	info, err := os.Stat(filepath.Join(remote, "TeslaUSB/TeslaCam/SavedClips/2024-05-01_18-22-10/front.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 2048 {
+		t.Errorf("expected 2048 bytes on server, got %d", info.Size())
+	}
+	for _, c := range clips {
+		if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+			t.Errorf("source %s should be removed", c.Key())
+		}
+	}
+}
+
+func TestWebDAVBearerAuth(t *testing.T) {
+	a, _ := newTestWebDAV(t, config.WebDAV{Token: "tok"})
+	if err := a.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebDAVAuthFailure(t *testing.T) {
+	root := t.TempDir()
+	writeClip(t, root, "TeslaCam/SentryClips/ev/left.mp4", 128)
+
+	a, _ := newTestWebDAV(t, config.WebDAV{Username: "alice", Password: "wrong"})
+	if err := a.Prepare(context.Background()); err == nil {
+		t.Error("expected authentication error")
+	}
+	clips := collectClips(root, []string{"TeslaCam/SentryClips"})
+	if _, err := a.Transfer(context.Background(), clips, nil); err == nil {
+		t.Error("expected transfer error")
+	}
+	if _, err := os.Stat(clips[0].Path); err != nil {
+		t.Error("source should be kept after a failed upload")
+	}
+}
+
+func TestWebDAVPruner(t *testing.T) {
+	a, remote := newTestWebDAV(t, config.WebDAV{Token: "tok"})
+	writeClip(t, remote, "TeslaUSB/TeslaCam/SentryClips/2024-05-01_18-22-10/front.mp4", 300)
+	writeClip(t, remote, "TeslaUSB/TeslaCam/SentryClips/2024-05-01_18-22-10/event.json", 20)
+	writeClip(t, remote, "TeslaUSB/TeslaCam/SavedClips/2024-05-02_18-22-10/front.mp4", 10)
+	p := a.(Pruner)
+	ctx := context.Background()
+
+	files, err := p.ListStored(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %+v", files)
+	}
+	for _, ev := range storedEvents(files) {
+		if ev.Category != "SentryClips" {
+			continue
+		}
+		if ev.Bytes != 320 || ev.dir != "TeslaCam/SentryClips/2024-05-01_18-22-10" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+		if err := p.RemoveStored(ctx, ev.dir, ev.files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(remote, "TeslaUSB/TeslaCam/SentryClips/2024-05-01_18-22-10")); !os.IsNotExist(err) {
+		t.Error("expected event folder deleted")
+	}
+	if _, err := os.Stat(filepath.Join(remote, "TeslaUSB/TeslaCam/SavedClips/2024-05-02_18-22-10/front.mp4")); err != nil {
+		t.Errorf("other events must be kept: %v", err)
+	}
+}
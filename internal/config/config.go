@@ -10,16 +10,107 @@ import (
 type Config struct {
 	NFS           NFS           `yaml:"nfs" json:"nfs"`
 	CIFS          CIFS          `yaml:"cifs" json:"cifs"`
+	S3            S3            `yaml:"s3" json:"s3"`
+	SFTP          SFTP          `yaml:"sftp" json:"sftp"`
+	WebDAV        WebDAV        `yaml:"webdav" json:"webdav"`
 	Archive       Archive       `yaml:"archive" json:"archive"`
 	KeepAwake     KeepAwake     `yaml:"keep_awake" json:"keep_awake"`
 	Notifications Notifications `yaml:"notifications" json:"notifications"`
 	Temperature   Temperature   `yaml:"temperature" json:"temperature"`
+	Home          Home          `yaml:"home" json:"home"`
+	Drives        Drives        `yaml:"drives" json:"drives"`
+
+	// Targets lists archive destinations in priority order. When empty,
+	// archive.method and the backend sections above form the only target.
+	Targets []Target `yaml:"targets,omitempty" json:"targets"`
+}
+
+// Target is one archive destination with its own backend settings.
+// Only the section matching Method is used.
+type Target struct {
+	Name   string `yaml:"name" json:"name"`
+	Method string `yaml:"method" json:"method"`
+	NFS    NFS    `yaml:"nfs,omitempty" json:"nfs"`
+	CIFS   CIFS   `yaml:"cifs,omitempty" json:"cifs"`
+	S3     S3     `yaml:"s3,omitempty" json:"s3"`
+	SFTP   SFTP   `yaml:"sftp,omitempty" json:"sftp"`
+	WebDAV WebDAV `yaml:"webdav,omitempty" json:"webdav"`
+}
+
+// ForTarget returns a copy of c whose archive method and backend settings
+// are those of t, for building t's archiver.
+func (c *Config) ForTarget(t Target) *Config {
+	cp := *c
+	cp.Archive.Method = t.Method
+	cp.NFS, cp.CIFS, cp.S3, cp.SFTP, cp.WebDAV = t.NFS, t.CIFS, t.S3, t.SFTP, t.WebDAV
+	cp.Targets = nil
+	return &cp
 }
 
 type Archive struct {
 	RecentClips    bool   `yaml:"recent_clips" json:"recent_clips"`
 	ReservePercent int    `yaml:"reserve_percent" json:"reserve_percent"`
-	Method         string `yaml:"method" json:"method"` // "nfs" or "cifs"
+	Method         string `yaml:"method" json:"method"`               // archive backend, e.g. "nfs" or "cifs"
+	Verify         string `yaml:"verify" json:"verify"`               // "", "sha256" or "xxhash": checksum copies on nfs/cifs before deleting
+	PathTemplate   string `yaml:"path_template" json:"path_template"` // destination folder per event; empty keeps the cam disk layout
+	Engine         string `yaml:"engine" json:"engine"`               // "rsync" (default) or "native": how nfs/cifs copy clips
+	Snapshot       bool   `yaml:"snapshot" json:"snapshot"`           // archive from a reflink snapshot, keeping the drive attached
+
+	// EmergencyFreePercent lets free-space management delete clips that
+	// haven't been archived once free space drops below this percentage of
+	// the cam disk; 0 never deletes them
+	EmergencyFreePercent int `yaml:"emergency_free_percent" json:"emergency_free_percent"`
+
+	BandwidthLimitKB int             `yaml:"bandwidth_limit_kb" json:"bandwidth_limit_kb"` // KiB/s, 0 = unlimited
+	Workers          int             `yaml:"workers" json:"workers"`                       // clips (rsync: folders) transferred at once, 0 or 1 = one
+	Windows          []ArchiveWindow `yaml:"windows" json:"windows"`                       // when archiving may start; empty = any time
+
+	Retention       []RetentionRule `yaml:"retention" json:"retention"`                 // pruning of the archive destination after each run
+	RetentionDryRun bool            `yaml:"retention_dry_run" json:"retention_dry_run"` // report what retention would prune without deleting
+
+	Filters []FilterRule `yaml:"filters" json:"filters"` // first matching rule decides each clip; unmatched clips are archived
+
+	WakeOnLAN WakeOnLAN `yaml:"wake_on_lan" json:"wake_on_lan"`
+}
+
+// WakeOnLAN wakes a sleeping archive server when the Pi joins the home
+// network, then waits up to TimeoutSeconds for a target to become reachable.
+type WakeOnLAN struct {
+	MAC            string `yaml:"mac" json:"mac"`                         // server's MAC address; empty disables
+	Broadcast      string `yaml:"broadcast" json:"broadcast"`             // defaults to 255.255.255.255
+	Port           int    `yaml:"port" json:"port"`                       // defaults to 9
+	TimeoutSeconds int    `yaml:"timeout_seconds" json:"timeout_seconds"` // defaults to 180
+}
+
+// FilterRule picks what happens to the clips it matches: "archive" (keep
+// on the cam disk too), "archive_delete" (remove once archived), "skip"
+// (leave on the cam disk) or "delete" (remove without archiving).
+// Empty fields match anything; cameras and reasons may use glob patterns.
+type FilterRule struct {
+	Categories []string `yaml:"categories" json:"categories"`     // SavedClips, SentryClips, RecentClips
+	Cameras    []string `yaml:"cameras" json:"cameras"`           // from the clip name, e.g. "front", "left_repeater"
+	Reasons    []string `yaml:"reasons" json:"reasons"`           // event.json reason, e.g. "sentry_aware_*"
+	MinAgeDays int      `yaml:"min_age_days" json:"min_age_days"` // only clips at least this old
+	MaxAgeDays int      `yaml:"max_age_days" json:"max_age_days"` // only clips at most this old
+	Action     string   `yaml:"action" json:"action"`
+}
+
+// RetentionRule limits how much of one category ("SavedClips",
+// "SentryClips" or "RecentClips") is kept on the archive destination.
+// Zero limits are unlimited; the oldest events are pruned first.
+type RetentionRule struct {
+	Category   string  `yaml:"category" json:"category"`
+	MaxAgeDays int     `yaml:"max_age_days" json:"max_age_days"`
+	MaxSizeGB  float64 `yaml:"max_size_gb" json:"max_size_gb"`
+}
+
+// ArchiveWindow allows archiving between Start and End ("HH:MM", local time)
+// on Days ("mon".."sun", empty = every day). An End before Start runs past
+// midnight.
+type ArchiveWindow struct {
+	Days  []string `yaml:"days" json:"days"`
+	Start string   `yaml:"start" json:"start"`
+	End   string   `yaml:"end" json:"end"`
 }
 
 type CIFS struct {
@@ -34,6 +125,36 @@ type NFS struct {
 	Share  string `yaml:"share" json:"share"`
 }
 
+// S3 configures an S3-compatible object store (AWS, MinIO, Backblaze B2, ...).
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" json:"endpoint"` // e.g. "https://s3.us-west-002.backblazeb2.com"
+	Region    string `yaml:"region" json:"region"`
+	Bucket    string `yaml:"bucket" json:"bucket"`
+	Prefix    string `yaml:"prefix" json:"prefix"`
+	AccessKey string `yaml:"access_key" json:"access_key"`
+	SecretKey string `yaml:"secret_key" json:"secret_key"`
+	PathStyle bool   `yaml:"path_style" json:"path_style"` // required by MinIO and most self-hosted servers
+}
+
+// SFTP configures an SSH server that clips are uploaded to without mounting.
+// Authentication uses the key pair under /mutable/teslausb.
+type SFTP struct {
+	Server   string `yaml:"server" json:"server"`
+	Port     int    `yaml:"port" json:"port"` // defaults to 22
+	Username string `yaml:"username" json:"username"`
+	Path     string `yaml:"path" json:"path"`         // remote directory clips are stored under
+	HostKey  string `yaml:"host_key" json:"host_key"` // optional pinned key, e.g. "ssh-ed25519 AAAA..."
+}
+
+// WebDAV configures a WebDAV collection such as a Nextcloud folder.
+// A bearer token takes precedence over username/password.
+type WebDAV struct {
+	URL      string `yaml:"url" json:"url"` // e.g. "https://cloud.example.com/remote.php/dav/files/alice/TeslaCam"
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	Token    string `yaml:"token" json:"token"`
+}
+
 type KeepAwake struct {
 	Method     string `yaml:"method" json:"method"` // "ble" or "webhook"
 	VIN        string `yaml:"vin" json:"vin"`
@@ -44,6 +165,28 @@ type Notifications struct {
 	WebhookURL string `yaml:"webhook_url" json:"webhook_url"`
 }
 
+// Home identifies the home network. The Pi is home when the WiFi SSID or
+// the default gateway (IP or MAC address) is listed; with neither set, when
+// an archive target is reachable. Presence is checked every 30 seconds and
+// only changes after several checks in a row agree.
+type Home struct {
+	SSIDs    []string `yaml:"ssids" json:"ssids"`
+	Gateways []string `yaml:"gateways" json:"gateways"`
+
+	ArriveChecks int `yaml:"arrive_checks" json:"arrive_checks"` // checks before arriving, default 2
+	LeaveChecks  int `yaml:"leave_checks" json:"leave_checks"`   // checks before leaving, default 4
+}
+
+// Drives sizes the optional USB drives exposed next to the cam disk, in GB;
+// 0 leaves a drive out. A new cam disk leaves room for them; one created
+// before they were configured took all the free space and must be
+// recreated.
+type Drives struct {
+	MusicGB     int `yaml:"music_gb" json:"music_gb"`
+	LightShowGB int `yaml:"lightshow_gb" json:"lightshow_gb"`
+	BoomboxGB   int `yaml:"boombox_gb" json:"boombox_gb"`
+}
+
 type Temperature struct {
 	WarningCelsius float64 `yaml:"warning_celsius" json:"warning_celsius"`
 	CautionCelsius float64 `yaml:"caution_celsius" json:"caution_celsius"`
@@ -69,10 +212,16 @@ func Load(path string) (*Config, error) {
 	if cfg.Archive.ReservePercent < 1 || cfg.Archive.ReservePercent > 50 {
 		cfg.Archive.ReservePercent = 10
 	}
-	// Default archive method to NFS
-	if cfg.Archive.Method != "nfs" && cfg.Archive.Method != "cifs" {
+	// Default archive method to NFS; backend-specific settings are
+	// validated by the archive package
+	if cfg.Archive.Method == "" {
 		cfg.Archive.Method = "nfs"
 	}
+	for i := range cfg.Targets {
+		if cfg.Targets[i].Method == "" {
+			cfg.Targets[i].Method = "nfs"
+		}
+	}
 	mu.Lock()
 	current = &cfg
 	mu.Unlock()
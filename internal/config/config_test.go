@@ -65,3 +65,61 @@ func TestSaveAndReload(t *testing.T) {
 		t.Errorf("expected 10.0.0.1, got %s", loaded.NFS.Server)
 	}
 }
+
+func TestLoadConfigArchiveMethod(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("nfs:\n  server: test\n"), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Archive.Method != "nfs" {
+		t.Errorf("expected default nfs, got %s", cfg.Archive.Method)
+	}
+
+	// Unknown methods are left for the archive package to reject
+	os.WriteFile(path, []byte("archive:\n  method: custom\n"), 0644)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Archive.Method != "custom" {
+		t.Errorf("expected custom, got %s", cfg.Archive.Method)
+	}
+}
+
+func TestLoadConfigTargets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`archive:
+  verify: xxhash
+targets:
+  - name: home
+    nfs:
+      server: nas
+      share: /data
+  - name: family
+    method: cifs
+    cifs:
+      server: nas2
+      share: TeslaCam
+`), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Targets) != 2 || cfg.Targets[0].Method != "nfs" || cfg.Targets[1].Name != "family" {
+		t.Fatalf("unexpected targets: %+v", cfg.Targets)
+	}
+
+	tc := cfg.ForTarget(cfg.Targets[1])
+	if tc.Archive.Method != "cifs" || tc.CIFS.Server != "nas2" || tc.Archive.Verify != "xxhash" {
+		t.Errorf("ForTarget: method %q, server %q, verify %q", tc.Archive.Method, tc.CIFS.Server, tc.Archive.Verify)
+	}
+	if len(tc.Targets) != 0 || cfg.Archive.Method != "nfs" {
+		t.Error("ForTarget modified the original config")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+
+	"github.com/teslausb-go/teslausb/internal/config"
 )
 
 const (
@@ -16,56 +18,124 @@ const (
 	MountPoint  = "/mnt/cam"
 )
 
+// Image is a backing file the car sees as one USB drive, formatted as a
+// single exFAT partition.
+type Image struct {
+	Name  string // e.g. "cam", used in logs
+	File  string
+	Label string   // exFAT volume label, also in the drive's inquiry string
+	Mount string   // where it is mounted locally while the car doesn't have it
+	Dirs  []string // folders the car expects, created on every mount
+	Size  int64    // bytes, for extra drives; the cam disk takes the free space
+}
+
+// Cam is the dashcam drive, always the first LUN.
+var Cam = Image{
+	Name:  "cam",
+	File:  BackingFile,
+	Label: "CAM",
+	Mount: MountPoint,
+	Dirs:  []string{"TeslaCam/RecentClips", "TeslaCam/SavedClips", "TeslaCam/SentryClips"},
+}
+
+// Extra returns the extra drives sized in cfg, in LUN order after the cam
+// disk. Tesla reads music from any folder, light shows from LightShow and
+// Boombox sounds from Boombox.
+func Extra(cfg config.Drives) []Image {
+	var imgs []Image
+	add := func(gb int, img Image) {
+		if gb > 0 {
+			img.Size = int64(gb) * 1024 * 1024 * 1024
+			imgs = append(imgs, img)
+		}
+	}
+	add(cfg.MusicGB, Image{Name: "music", File: filepath.Join(BackingDir, "music_disk.bin"), Label: "MUSIC", Mount: "/mnt/music"})
+	add(cfg.LightShowGB, Image{Name: "lightshow", File: filepath.Join(BackingDir, "lightshow_disk.bin"), Label: "LIGHTSHOW", Mount: "/mnt/lightshow", Dirs: []string{"LightShow"}})
+	add(cfg.BoomboxGB, Image{Name: "boombox", File: filepath.Join(BackingDir, "boombox_disk.bin"), Label: "BOOMBOX", Mount: "/mnt/boombox", Dirs: []string{"Boombox"}})
+	return imgs
+}
+
 func Exists() bool {
-	_, err := os.Stat(BackingFile)
+	return Cam.Exists()
+}
+
+func (img Image) Exists() bool {
+	_, err := os.Stat(img.File)
 	return err == nil
 }
 
-// Create creates the cam disk image with auto-sized exFAT.
-func Create() error {
+// headroom is left free on the backing filesystem.
+const headroom = 500 * 1024 * 1024
+
+// Create creates the cam disk image with auto-sized exFAT, leaving room for
+// the extra images that don't exist yet.
+func Create(extra []Image) error {
 	if Exists() {
 		log.Println("cam_disk.bin already exists")
 		return nil
 	}
 
-	// Calculate available space
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(BackingDir, &stat); err != nil {
-		return fmt.Errorf("statfs: %w", err)
+	available, err := freeSpace()
+	if err != nil {
+		return err
+	}
+	reserve := int64(headroom)
+	for _, img := range extra {
+		if !img.Exists() {
+			reserve += img.Size
+		}
 	}
-	available := int64(stat.Bavail) * int64(stat.Bsize)
-	reserve := int64(500 * 1024 * 1024) // 500MB headroom
 	size := available - reserve
 	if size < 1024*1024*1024 { // minimum 1GB
 		return fmt.Errorf("not enough space: %d bytes available", available)
 	}
 
-	log.Printf("creating cam_disk.bin: %d GB", size/(1024*1024*1024))
+	img := Cam
+	img.Size = size
+	return img.Create()
+}
+
+// Create creates the image at img.Size, partitions and formats it, and
+// creates img.Dirs.
+func (img Image) Create() error {
+	if img.Exists() {
+		log.Printf("%s already exists", filepath.Base(img.File))
+		return nil
+	}
+	available, err := freeSpace()
+	if err != nil {
+		return err
+	}
+	if img.Size > available-headroom {
+		return fmt.Errorf("not enough space for %s: %d bytes available", img.Name, available)
+	}
+
+	log.Printf("creating %s: %d GB", filepath.Base(img.File), img.Size/(1024*1024*1024))
 
 	// Create sparse file
-	f, err := os.Create(BackingFile)
+	f, err := os.Create(img.File)
 	if err != nil {
 		return fmt.Errorf("create file: %w", err)
 	}
-	if err := f.Truncate(size); err != nil {
+	if err := f.Truncate(img.Size); err != nil {
 		f.Close()
-		os.Remove(BackingFile)
+		os.Remove(img.File)
 		return fmt.Errorf("truncate: %w", err)
 	}
 	f.Close()
 
 	// Create partition table
-	cmd := exec.Command("sfdisk", BackingFile)
+	cmd := exec.Command("sfdisk", img.File)
 	cmd.Stdin = strings.NewReader("type=7\n")
 	if out, err := cmd.CombinedOutput(); err != nil {
-		os.Remove(BackingFile)
+		os.Remove(img.File)
 		return fmt.Errorf("sfdisk: %s: %w", out, err)
 	}
 
 	// Setup loop device with partition scan
-	out, err := exec.Command("losetup", "--find", "--show", "--partscan", BackingFile).Output()
+	out, err := exec.Command("losetup", "--find", "--show", "--partscan", img.File).Output()
 	if err != nil {
-		os.Remove(BackingFile)
+		os.Remove(img.File)
 		return fmt.Errorf("losetup: %w", err)
 	}
 	loopDev := strings.TrimSpace(string(out))
@@ -73,31 +143,76 @@ func Create() error {
 	defer exec.Command("losetup", "-d", loopDev).Run()
 
 	// Format as exFAT
-	if out, err := exec.Command("mkfs.exfat", "-L", "CAM", partDev).CombinedOutput(); err != nil {
-		os.Remove(BackingFile)
+	if out, err := exec.Command("mkfs.exfat", "-L", img.Label, partDev).CombinedOutput(); err != nil {
+		os.Remove(img.File)
 		return fmt.Errorf("mkfs.exfat: %s: %w", out, err)
 	}
 
-	// Mount and create TeslaCam directory structure Tesla expects
-	os.MkdirAll(MountPoint, 0755)
-	if err := exec.Command("mount", partDev, MountPoint).Run(); err != nil {
+	// Mount and create the directory structure Tesla expects
+	os.MkdirAll(img.Mount, 0755)
+	if err := exec.Command("mount", partDev, img.Mount).Run(); err != nil {
 		return fmt.Errorf("mount: %w", err)
 	}
-	for _, dir := range []string{"TeslaCam/RecentClips", "TeslaCam/SavedClips", "TeslaCam/SentryClips"} {
-		os.MkdirAll(filepath.Join(MountPoint, dir), 0755)
+	for _, dir := range img.Dirs {
+		os.MkdirAll(filepath.Join(img.Mount, dir), 0755)
 	}
-	exec.Command("umount", MountPoint).Run()
+	exec.Command("umount", img.Mount).Run()
+
+	log.Printf("%s created and formatted (%d GB exFAT)", filepath.Base(img.File), img.Size/(1024*1024*1024))
+	return nil
+}
 
-	log.Printf("cam_disk.bin created and formatted (%d GB exFAT)", size/(1024*1024*1024))
+// CheckSpace returns an error if the extra images that don't exist yet
+// don't fit next to the cam disk. Create leaves room for them, but a cam
+// disk created before they were configured took all the free space.
+func CheckSpace(extra []Image) error {
+	if !Exists() {
+		return nil
+	}
+	var need int64
+	var names []string
+	for _, img := range extra {
+		if !img.Exists() {
+			need += img.Size
+			names = append(names, img.Name)
+		}
+	}
+	if need == 0 {
+		return nil
+	}
+	available, err := freeSpace()
+	if err != nil {
+		return err
+	}
+	if free := max(0, available-headroom); need > free {
+		return fmt.Errorf("no room for the %s drive: %d GB needed but only %d GB is free next to the cam disk, "+
+			"which took the free space when it was created; delete %s (and its clips) to have it recreated smaller",
+			strings.Join(names, " and "), need/(1024*1024*1024), free/(1024*1024*1024), BackingFile)
+	}
 	return nil
 }
 
+// freeSpace returns the bytes available for new images.
+func freeSpace() (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(BackingDir, &stat); err != nil {
+		return 0, fmt.Errorf("statfs: %w", err)
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
 // Mount mounts the cam disk image locally after running fsck.
 func Mount() error {
-	os.MkdirAll(MountPoint, 0755)
+	return Cam.MountLocal()
+}
+
+// MountLocal mounts the image at img.Mount after running fsck. The car must
+// not have it at the same time.
+func (img Image) MountLocal() error {
+	os.MkdirAll(img.Mount, 0755)
 
 	// Setup loop device
-	out, err := exec.Command("losetup", "--find", "--show", "--partscan", BackingFile).Output()
+	out, err := exec.Command("losetup", "--find", "--show", "--partscan", img.File).Output()
 	if err != nil {
 		return fmt.Errorf("losetup: %w", err)
 	}
@@ -105,39 +220,37 @@ func Mount() error {
 	partDev := loopDev + "p1"
 
 	// fsck (repair mode)
-	log.Println("running fsck.exfat on cam image...")
+	log.Printf("running fsck.exfat on %s image...", img.Name)
 	exec.Command("fsck.exfat", "-p", partDev).Run() // ignore errors, best-effort
 
 	// Mount
-	if err := exec.Command("mount", "-o", "umask=000", partDev, MountPoint).Run(); err != nil {
+	if err := exec.Command("mount", "-o", "umask=000", partDev, img.Mount).Run(); err != nil {
 		exec.Command("losetup", "-d", loopDev).Run()
 		return fmt.Errorf("mount: %w", err)
 	}
 
-	// Ensure TeslaCam subdirectories exist (fixes existing images missing them)
-	for _, dir := range []string{"TeslaCam/RecentClips", "TeslaCam/SavedClips", "TeslaCam/SentryClips"} {
-		os.MkdirAll(filepath.Join(MountPoint, dir), 0755)
+	// Ensure expected subdirectories exist (fixes existing images missing them)
+	for _, dir := range img.Dirs {
+		os.MkdirAll(filepath.Join(img.Mount, dir), 0755)
 	}
 
-	log.Println("cam image mounted at", MountPoint)
+	log.Printf("%s image mounted at %s", img.Name, img.Mount)
 	return nil
 }
 
 // Unmount unmounts the cam disk image and detaches the loop device.
 func Unmount() error {
-	exec.Command("umount", MountPoint).Run()
+	return Cam.Unmount()
+}
+
+// Unmount unmounts the image and detaches its loop device.
+func (img Image) Unmount() error {
+	exec.Command("umount", img.Mount).Run()
 
 	// Find and detach loop device for our backing file
-	out, err := exec.Command("losetup", "-j", BackingFile).Output()
-	if err == nil {
-		for _, line := range strings.Split(string(out), "\n") {
-			if parts := strings.SplitN(line, ":", 2); len(parts) > 0 && parts[0] != "" {
-				exec.Command("losetup", "-d", parts[0]).Run()
-			}
-		}
-	}
+	detachLoop(img.File)
 
-	log.Println("cam image unmounted")
+	log.Printf("%s image unmounted", img.Name)
 	return nil
 }
 
@@ -2,6 +2,8 @@ package disk
 
 import (
 	"testing"
+
+	"github.com/teslausb-go/teslausb/internal/config"
 )
 
 func TestExists(t *testing.T) {
@@ -15,3 +17,26 @@ func TestCleanArtifacts(t *testing.T) {
 	// Should not panic when mount point doesn't exist
 	CleanArtifacts()
 }
+
+func TestExtra(t *testing.T) {
+	if imgs := Extra(config.Drives{}); len(imgs) != 0 {
+		t.Fatalf("no sizes: got %d drives", len(imgs))
+	}
+	imgs := Extra(config.Drives{MusicGB: 8, BoomboxGB: 1})
+	if len(imgs) != 2 {
+		t.Fatalf("got %d drives, want 2", len(imgs))
+	}
+	if imgs[0].Label != "MUSIC" || imgs[0].Size != 8<<30 {
+		t.Errorf("first drive = %s, %d bytes", imgs[0].Label, imgs[0].Size)
+	}
+	if imgs[1].Label != "BOOMBOX" || len(imgs[1].Dirs) != 1 || imgs[1].Dirs[0] != "Boombox" {
+		t.Errorf("second drive = %s %v", imgs[1].Label, imgs[1].Dirs)
+	}
+	seen := map[string]bool{Cam.File: true}
+	for _, img := range Extra(config.Drives{MusicGB: 1, LightShowGB: 1, BoomboxGB: 1}) {
+		if seen[img.File] {
+			t.Errorf("%s shares backing file %s", img.Name, img.File)
+		}
+		seen[img.File] = true
+	}
+}
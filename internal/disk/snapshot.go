@@ -0,0 +1,132 @@
+package disk
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	SnapshotDir   = "/backingfiles/snapshots" // reflink copies of cam_disk.bin
+	SnapshotMount = "/mnt/snapshots"          // each snapshot is mounted in a subdirectory
+)
+
+// Snapshot is a point-in-time copy of the cam disk image, sharing its
+// blocks with the live image by reflink, loop-mounted read-only at Dir.
+// Taking and reading one never writes to the image the car is using.
+type Snapshot struct {
+	Name  string
+	File  string
+	Dir   string
+	Taken time.Time
+}
+
+// TakeSnapshot reflinks cam_disk.bin to a snapshot named name and mounts it
+// read-only. The backing filesystem must support reflinks (XFS or btrfs);
+// there is never a full copy.
+func TakeSnapshot(name string) (*Snapshot, error) {
+	s := &Snapshot{
+		Name:  name,
+		File:  filepath.Join(SnapshotDir, name+".bin"),
+		Dir:   filepath.Join(SnapshotMount, name),
+		Taken: time.Now(),
+	}
+	if err := os.MkdirAll(SnapshotDir, 0755); err != nil {
+		return nil, err
+	}
+	os.Remove(s.File)
+	if out, err := exec.Command("cp", "--reflink=always", BackingFile, s.File).CombinedOutput(); err != nil {
+		os.Remove(s.File)
+		return nil, fmt.Errorf("reflink snapshot (needs XFS or btrfs): %s: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	out, err := exec.Command("losetup", "--find", "--show", "--partscan", s.File).Output()
+	if err != nil {
+		os.Remove(s.File)
+		return nil, fmt.Errorf("losetup: %w", err)
+	}
+	loopDev := strings.TrimSpace(string(out))
+	partDev := loopDev + "p1"
+
+	// The copy may have been taken mid-write; repairing it only touches
+	// the snapshot's own copy-on-write blocks
+	exec.Command("fsck.exfat", "-p", partDev).Run()
+
+	os.MkdirAll(s.Dir, 0755)
+	if out, err := exec.Command("mount", "-o", "ro,umask=022", partDev, s.Dir).CombinedOutput(); err != nil {
+		exec.Command("losetup", "-d", loopDev).Run()
+		os.Remove(s.File)
+		return nil, fmt.Errorf("mount snapshot: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	log.Printf("cam snapshot %s mounted at %s", name, s.Dir)
+	return s, nil
+}
+
+// Release unmounts the snapshot and deletes its image.
+func (s *Snapshot) Release() {
+	releaseSnapshot(s.Dir, s.File)
+	log.Printf("cam snapshot %s released", s.Name)
+}
+
+func releaseSnapshot(dir, file string) {
+	exec.Command("umount", dir).Run()
+	os.Remove(dir)
+	detachLoop(file)
+	os.Remove(file)
+}
+
+// CleanSnapshots releases snapshots left behind by a previous process.
+func CleanSnapshots() {
+	entries, _ := os.ReadDir(SnapshotDir)
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".bin")
+		if !ok {
+			continue
+		}
+		releaseSnapshot(filepath.Join(SnapshotMount, name), filepath.Join(SnapshotDir, e.Name()))
+		log.Printf("removed stale cam snapshot %s", name)
+	}
+}
+
+// Mounted reports whether the cam disk is mounted at MountPoint, which is
+// only the case while the gadget is disabled for archiving.
+func Mounted() bool {
+	ok, err := isMountPoint("/proc/self/mounts", MountPoint)
+	return err == nil && ok
+}
+
+func isMountPoint(mounts, dir string) (bool, error) {
+	f, err := os.Open(mounts)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[1] == dir {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// detachLoop detaches every loop device backed by file.
+func detachLoop(file string) {
+	out, err := exec.Command("losetup", "-j", file).Output()
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if dev, _, ok := strings.Cut(line, ":"); ok && dev != "" {
+			exec.Command("losetup", "-d", dev).Run()
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsMountPoint(t *testing.T) {
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	os.WriteFile(mounts, []byte(`/dev/mmcblk0p2 / ext4 rw,noatime 0 0
+/dev/loop0p1 /mnt/cam exfat rw,relatime,umask=000 0 0
+/dev/loop1p1 /mnt/snapshots/browse-1 exfat ro,relatime 0 0
+`), 0644)
+
+	for dir, want := range map[string]bool{"/mnt/cam": true, "/mnt/snapshots/browse-1": true, "/mnt/archive": false, "/mnt": false} {
+		got, err := isMountPoint(mounts, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("isMountPoint(%s) = %v, want %v", dir, got, want)
+		}
+	}
+	if _, err := isMountPoint(filepath.Join(t.TempDir(), "missing"), "/mnt/cam"); err == nil {
+		t.Error("expected error for missing mounts table")
+	}
+}
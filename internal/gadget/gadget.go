@@ -60,7 +60,19 @@ func detectMaxPower() string {
 	}
 }
 
-func Enable(backingFile string) error {
+// LUN is one drive of the mass storage function, backed by an image file.
+type LUN struct {
+	File  string
+	Label string // shown in the inquiry string, e.g. "CAM"
+}
+
+// Enable exposes luns to the car as drives of one mass storage function,
+// in order: the first is lun.0.
+func Enable(luns ...LUN) error {
+	if len(luns) == 0 {
+		return fmt.Errorf("no LUNs to enable")
+	}
+
 	// Unload g_ether placeholder
 	exec.Command("modprobe", "-r", "g_ether").Run()
 
@@ -102,11 +114,17 @@ func Enable(backingFile string) error {
 	writeFile(filepath.Join(root, "configs", "c.1", "strings", "0x409", "configuration"), "TeslaUSB Config")
 	writeFile(filepath.Join(root, "configs", "c.1", "MaxPower"), detectMaxPower())
 
-	// Mass storage LUN
-	writeFile(filepath.Join(root, "functions", "mass_storage.0", "lun.0", "file"), backingFile)
-	if info, err := os.Stat(backingFile); err == nil {
-		sizeGB := info.Size() / (1024 * 1024 * 1024)
-		writeFile(filepath.Join(root, "functions", "mass_storage.0", "lun.0", "inquiry_string"), fmt.Sprintf("TeslaUSB CAM %dG", sizeGB))
+	// Mass storage LUNs; lun.0 comes with the function, the rest are created
+	for i, lun := range luns {
+		dir := filepath.Join(root, "functions", "mass_storage.0", fmt.Sprintf("lun.%d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("mkdir %s: %w", dir, err)
+		}
+		writeFile(filepath.Join(dir, "file"), lun.File)
+		if info, err := os.Stat(lun.File); err == nil {
+			sizeGB := info.Size() / (1024 * 1024 * 1024)
+			writeFile(filepath.Join(dir, "inquiry_string"), fmt.Sprintf("TeslaUSB %s %dG", lun.Label, sizeGB))
+		}
 	}
 
 	// Symlink function to config
@@ -132,7 +150,11 @@ func Enable(backingFile string) error {
 	}
 	writeFile(filepath.Join(root, "UDC"), udcName)
 
-	log.Printf("USB gadget enabled with %s", backingFile)
+	files := make([]string, len(luns))
+	for i, lun := range luns {
+		files[i] = lun.File
+	}
+	log.Printf("USB gadget enabled with %s", strings.Join(files, ", "))
 	return nil
 }
 
@@ -145,6 +167,14 @@ func Disable() error {
 	// Remove symlink
 	os.Remove(filepath.Join(root, "configs", "c.1", "mass_storage.0"))
 
+	// Remove the extra LUNs; lun.0 goes with the function
+	luns, _ := filepath.Glob(filepath.Join(root, "functions", "mass_storage.0", "lun.*"))
+	for _, dir := range luns {
+		if filepath.Base(dir) != "lun.0" {
+			os.Remove(dir)
+		}
+	}
+
 	// Remove dirs in reverse order
 	for _, dir := range []string{
 		filepath.Join(root, "configs", "c.1", "strings", "0x409"),
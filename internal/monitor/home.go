@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+// HomeMatch reports why n is the home network described by h, e.g.
+// `ssid "HomeWiFi"` or "gateway 192.168.1.1", or "" if it isn't.
+func (n NetworkInfo) HomeMatch(h config.Home) string {
+	if n.SSID != "" && slices.Contains(h.SSIDs, n.SSID) {
+		return fmt.Sprintf("ssid %q", n.SSID)
+	}
+	for _, g := range h.Gateways {
+		if n.Gateway != "" && g == n.Gateway {
+			return "gateway " + n.Gateway
+		}
+		if n.GatewayMAC != "" && strings.EqualFold(g, n.GatewayMAC) {
+			return "gateway " + n.GatewayMAC
+		}
+	}
+	return ""
+}
+
+func defaultGateway() string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return parseRoute(f)
+}
+
+// parseRoute returns the gateway of the default route in /proc/net/route,
+// where addresses are little-endian hex:
+//
+//	Iface  Destination  Gateway   Flags ...
+//	wlan0  00000000     0101A8C0  0003  ...
+func parseRoute(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+		if ip.IsUnspecified() {
+			continue
+		}
+		return ip.String()
+	}
+	return ""
+}
+
+func arpLookup(ip string) string {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return parseARP(f, ip)
+}
+
+// parseARP returns the hardware address of ip in /proc/net/arp:
+//
+//	IP address    HW type  Flags  HW address         Mask  Device
+//	192.168.1.1   0x1      0x2    aa:bb:cc:dd:ee:ff  *     wlan0
+func parseARP(r io.Reader, ip string) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != ip || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+		return fields[3]
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+)
+
+func TestParseRoute(t *testing.T) {
+	table := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+wlan0	0001A8C0	00000000	0001	0	0	303	00FFFFFF	0	0	0
+wlan0	00000000	0101A8C0	0003	0	0	303	00000000	0	0	0
+`
+	if got := parseRoute(strings.NewReader(table)); got != "192.168.1.1" {
+		t.Errorf("gateway = %q, want 192.168.1.1", got)
+	}
+	if got := parseRoute(strings.NewReader("Iface\tDestination\tGateway\n")); got != "" {
+		t.Errorf("gateway without default route = %q", got)
+	}
+}
+
+func TestParseARP(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        wlan0
+192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        wlan0
+`
+	if got := parseARP(strings.NewReader(table), "192.168.1.1"); got != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("mac = %q", got)
+	}
+	if got := parseARP(strings.NewReader(table), "192.168.1.7"); got != "" {
+		t.Errorf("incomplete entry = %q, want empty", got)
+	}
+}
+
+func TestHomeMatch(t *testing.T) {
+	home := config.Home{SSIDs: []string{"HomeWiFi"}, Gateways: []string{"192.168.1.1", "AA:BB:CC:DD:EE:FF"}}
+	tests := []struct {
+		name string
+		info NetworkInfo
+		want string
+	}{
+		{"ssid", NetworkInfo{SSID: "HomeWiFi"}, `ssid "HomeWiFi"`},
+		{"gateway ip", NetworkInfo{SSID: "Other", Gateway: "192.168.1.1"}, "gateway 192.168.1.1"},
+		{"gateway mac", NetworkInfo{Gateway: "10.0.0.1", GatewayMAC: "aa:bb:cc:dd:ee:ff"}, "gateway aa:bb:cc:dd:ee:ff"},
+		{"elsewhere", NetworkInfo{SSID: "Cafe", Gateway: "10.0.0.1", GatewayMAC: "11:22:33:44:55:66"}, ""},
+		{"offline", NetworkInfo{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.info.HomeMatch(home); got != tt.want {
+			t.Errorf("%s: HomeMatch = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -12,6 +12,9 @@ type NetworkInfo struct {
 	SSID      string `json:"wifi_ssid"`
 	SignalDBM int    `json:"wifi_signal_dbm"`
 	IP        string `json:"wifi_ip"`
+
+	Gateway    string `json:"gateway"`     // default gateway IPv4 address
+	GatewayMAC string `json:"gateway_mac"` // from the ARP cache, "" until resolved
 }
 
 func GetNetworkInfo() NetworkInfo {
@@ -49,5 +52,10 @@ func GetNetworkInfo() NetworkInfo {
 		}
 	}
 
+	info.Gateway = defaultGateway()
+	if info.Gateway != "" {
+		info.GatewayMAC = arpLookup(info.Gateway)
+	}
+
 	return info
 }
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/archive"
+)
+
+const historyFile = "/mutable/teslausb/archive_history.jsonl"
+
+// maxHistory bounds how many archive runs are kept.
+const maxHistory = 500
+
+// ArchiveRun records one archive attempt, successful or not.
+type ArchiveRun struct {
+	ID          int       `json:"id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Status      string    `json:"status"` // "success", "partial", "failed" or "cancelled"
+	Error       string    `json:"error,omitempty"`
+	BytesPerSec float64   `json:"bytes_per_sec"`
+	archive.Result
+}
+
+// History is an append-only log of archive runs, stored one JSON object
+// per line so a power cut can at worst lose the run being written.
+type History struct {
+	mu    sync.Mutex
+	path  string
+	max   int
+	runs  []ArchiveRun // oldest first
+	lines int          // lines in the file, including ones since dropped
+}
+
+// LoadHistory reads the history at path, keeping the newest max runs.
+// Unreadable lines are skipped.
+func LoadHistory(path string, max int) *History {
+	h := &History{path: path, max: max}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		h.lines++
+		var run ArchiveRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		h.runs = append(h.runs, run)
+	}
+	if len(h.runs) > max {
+		h.runs = h.runs[len(h.runs)-max:]
+	}
+	return h
+}
+
+// Append assigns run the next ID and stores it.
+func (h *History) Append(run ArchiveRun) ArchiveRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	run.ID = 1
+	if len(h.runs) > 0 {
+		run.ID = h.runs[len(h.runs)-1].ID + 1
+	}
+	h.runs = append(h.runs, run)
+	if len(h.runs) > h.max {
+		h.runs = h.runs[len(h.runs)-h.max:]
+	}
+
+	// Rewrite once the file holds twice the runs we keep
+	if h.lines+1 > 2*h.max {
+		if err := h.compact(); err != nil {
+			log.Printf("archive history: %v", err)
+		}
+		return run
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		log.Printf("archive history: %v", err)
+		return run
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("archive history: %v", err)
+		return run
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("archive history: %v", err)
+		return run
+	}
+	h.lines++
+	return run
+}
+
+func (h *History) compact() error {
+	tmp := h.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, run := range h.runs {
+		if err := enc.Encode(run); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	f.Close()
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+	h.lines = len(h.runs)
+	return nil
+}
+
+// List returns all kept runs, newest first.
+func (h *History) List() []ArchiveRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	runs := append([]ArchiveRun{}, h.runs...)
+	slices.Reverse(runs)
+	return runs
+}
+
+// Get returns the run with the given ID.
+func (h *History) Get(id int) (ArchiveRun, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, run := range h.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return ArchiveRun{}, false
+}
+
+// Throughput returns the mean BytesPerSec of the newest n runs that archived
+// anything, or 0 if there are none.
+func (h *History) Throughput(n int) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var sum float64
+	var count int
+	for i := len(h.runs) - 1; i >= 0 && count < n; i-- {
+		if run := h.runs[i]; run.Bytes > 0 && run.BytesPerSec > 0 {
+			sum += run.BytesPerSec
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/archive"
+)
+
+func TestHistoryAppendAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := LoadHistory(path, 3)
+	for i := 0; i < 5; i++ {
+		h.Append(ArchiveRun{Status: "success", Result: archive.Result{Tally: archive.Tally{Clips: i}}})
+	}
+	runs := h.List()
+	if len(runs) != 3 || runs[0].ID != 5 || runs[2].ID != 3 {
+		t.Fatalf("expected runs 5..3 newest first, got %+v", runs)
+	}
+
+	// A run cut short by power loss must not hide the others
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"id":6,"sta`)
+	f.Close()
+
+	h = LoadHistory(path, 3)
+	run, ok := h.Get(4)
+	if !ok || run.Clips != 3 {
+		t.Errorf("expected run 4 with 3 clips after reload, got %+v", run)
+	}
+	if next := h.Append(ArchiveRun{}); next.ID != 6 {
+		t.Errorf("expected next ID 6, got %d", next.ID)
+	}
+}
+
+func TestHistoryCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := LoadHistory(path, 2)
+	for i := 0; i < 10; i++ {
+		h.Append(ArchiveRun{})
+	}
+	h = LoadHistory(path, 2)
+	if h.lines > 4 {
+		t.Errorf("expected file compacted to at most 4 lines, got %d", h.lines)
+	}
+	if runs := h.List(); len(runs) != 2 || runs[0].ID != 10 {
+		t.Errorf("unexpected runs after compaction %+v", runs)
+	}
+}
+
+func TestRecordRunStatus(t *testing.T) {
+	m := &Machine{history: LoadHistory(filepath.Join(t.TempDir(), "h.jsonl"), 10)}
+	start := time.Now().Add(-2 * time.Second)
+	m.recordRun(start, archive.Result{Tally: archive.Tally{Clips: 2, Bytes: 2000}}, errors.New("rsync failed"))
+	m.recordRun(start, archive.Result{}, errors.New("mount failed"))
+	m.recordRun(start, archive.Result{Tally: archive.Tally{Clips: 1, Bytes: 100}}, nil)
+
+	runs := m.ArchiveHistory()
+	if runs[2].Status != "partial" || runs[1].Status != "failed" || runs[0].Status != "success" {
+		t.Errorf("unexpected statuses %s %s %s", runs[2].Status, runs[1].Status, runs[0].Status)
+	}
+	if runs[2].Error != "rsync failed" || runs[2].BytesPerSec <= 0 {
+		t.Errorf("unexpected run %+v", runs[2])
+	}
+}
+
+func TestHistoryThroughput(t *testing.T) {
+	h := LoadHistory(filepath.Join(t.TempDir(), "history.jsonl"), 10)
+	if got := h.Throughput(5); got != 0 {
+		t.Errorf("expected 0 without history, got %v", got)
+	}
+	h.Append(ArchiveRun{BytesPerSec: 1000, Result: archive.Result{Tally: archive.Tally{Bytes: 1}}})
+	h.Append(ArchiveRun{BytesPerSec: 100, Result: archive.Result{Tally: archive.Tally{Bytes: 1}}})
+	h.Append(ArchiveRun{Status: "failed"}) // archived nothing
+	h.Append(ArchiveRun{BytesPerSec: 300, Result: archive.Result{Tally: archive.Tally{Bytes: 1}}})
+	if got := h.Throughput(2); got != 200 {
+		t.Errorf("expected mean of the newest 2 runs with data (200), got %v", got)
+	}
+}
+
+func TestArchiveControlWhenIdle(t *testing.T) {
+	m := &Machine{history: LoadHistory(filepath.Join(t.TempDir(), "h.jsonl"), 10)}
+	if m.CancelArchive() || m.PauseArchive() || m.ResumeArchive() {
+		t.Error("expected archive controls to do nothing without a running archive")
+	}
+
+	m.recordRun(time.Now(), archive.Result{Tally: archive.Tally{Clips: 1, Skipped: 3}}, errCancelled)
+	if run := m.ArchiveHistory()[0]; run.Status != "cancelled" || run.Error != "archive cancelled" {
+		t.Errorf("expected a cancelled run, got %s %q", run.Status, run.Error)
+	}
+}
@@ -3,6 +3,7 @@ package state
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"github.com/teslausb-go/teslausb/internal/config"
 	"github.com/teslausb-go/teslausb/internal/disk"
 	"github.com/teslausb-go/teslausb/internal/gadget"
+	"github.com/teslausb-go/teslausb/internal/monitor"
 	"github.com/teslausb-go/teslausb/internal/notify"
 	"github.com/teslausb-go/teslausb/internal/system"
 	"github.com/teslausb-go/teslausb/internal/webhook"
@@ -34,8 +36,31 @@ const (
 type CumulativeStats struct {
 	TotalClips   int       `json:"total_clips"`
 	TotalBytes   int64     `json:"total_bytes"`
+	TotalEvents  int       `json:"total_events"`
+	TotalSkipped int       `json:"total_skipped"`
+	TotalFailed  int       `json:"total_failed"`
 	ArchiveCount int       `json:"archive_count"`
+	Cancelled    int       `json:"cancelled"` // runs stopped from the API
 	LastArchive  time.Time `json:"last_archive"`
+
+	Categories map[string]archive.Tally `json:"categories"`
+}
+
+// add accumulates the result of one archive run.
+func (s *CumulativeStats) add(res archive.Result) {
+	s.TotalClips += res.Clips
+	s.TotalBytes += res.Bytes
+	s.TotalEvents += res.Events
+	s.TotalSkipped += res.Skipped
+	s.TotalFailed += res.Failed
+	if s.Categories == nil {
+		s.Categories = map[string]archive.Tally{}
+	}
+	for name, t := range res.Categories {
+		cat := s.Categories[name]
+		cat.Add(t)
+		s.Categories[name] = cat
+	}
 }
 
 type Machine struct {
@@ -43,18 +68,29 @@ type Machine struct {
 	state         State
 	lastArchive   time.Time
 	lastError     string
-	archiveClips  int
-	archiveBytes  int64
+	lastResult    archive.Result
+	target        string // archive target in use, or last used
 	cumulative    CumulativeStats
 	gadgetEnabled bool
+	progress      archive.Progress
+	history       *History
+	journal       *archive.Journal
+	cancelRun     context.CancelFunc // stops the running archive, nil when idle
+	gate          *archive.Gate      // pauses the running archive
+	snapshot      *disk.Snapshot     // what a snapshot run archives from, nil otherwise
+	presence      Presence
 	listeners     []func(State)
+	progressFns   []func(archive.Progress)
 }
 
+// errCancelled records an archive run stopped from the API.
+var errCancelled = errors.New("archive cancelled")
+
 const lastArchiveFile = "/mutable/teslausb/last_archive"
 const statsFile = "/mutable/teslausb/stats.json"
 
 func New() *Machine {
-	m := &Machine{state: StateBooting}
+	m := &Machine{state: StateBooting, history: LoadHistory(historyFile, maxHistory)}
 	// Restore last archive timestamp
 	if data, err := os.ReadFile(lastArchiveFile); err == nil {
 		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
@@ -81,11 +117,15 @@ func (m *Machine) Info() map[string]any {
 		"state":               string(m.state),
 		"last_archive":        m.lastArchive,
 		"last_error":          m.lastError,
-		"archive_clips":       m.archiveClips,
-		"archive_bytes":       m.archiveBytes,
+		"archive_clips":       m.lastResult.Clips,
+		"archive_bytes":       m.lastResult.Bytes,
+		"archive_result":      m.lastResult,
+		"archive_target":      m.target,
 		"total_archive_clips": m.cumulative.TotalClips,
 		"total_archive_bytes": m.cumulative.TotalBytes,
 		"archive_count":       m.cumulative.ArchiveCount,
+		"cumulative":          m.cumulative,
+		"presence":            m.presence,
 	}
 }
 
@@ -98,12 +138,119 @@ func (m *Machine) TriggerArchive() bool {
 	return false
 }
 
+// CancelArchive stops a running archive once the files in flight are
+// finished (with rsync, the batch in flight). The run ends through the idle
+// state, which unmounts and re-enables the gadget.
+func (m *Machine) CancelArchive() bool {
+	m.mu.RLock()
+	cancel := m.cancelRun
+	m.mu.RUnlock()
+	if cancel == nil {
+		return false
+	}
+	log.Println("archive cancel requested")
+	cancel()
+	return true
+}
+
+// PauseArchive holds a running archive before its next file (with rsync,
+// its next batch of files).
+func (m *Machine) PauseArchive() bool {
+	m.mu.RLock()
+	g := m.gate
+	m.mu.RUnlock()
+	if g == nil || !g.Pause() {
+		return false
+	}
+	log.Println("archive paused")
+	p := m.ArchiveProgress()
+	p.Paused = true
+	m.reportProgress(p)
+	return true
+}
+
+// ResumeArchive continues a paused archive.
+func (m *Machine) ResumeArchive() bool {
+	m.mu.RLock()
+	g := m.gate
+	m.mu.RUnlock()
+	if g == nil || !g.Resume() {
+		return false
+	}
+	log.Println("archive resumed")
+	p := m.ArchiveProgress()
+	p.Paused = false
+	m.reportProgress(p)
+	return true
+}
+
 func (m *Machine) OnStateChange(fn func(State)) {
 	m.mu.Lock()
 	m.listeners = append(m.listeners, fn)
 	m.mu.Unlock()
 }
 
+// OnArchiveProgress registers fn to receive progress snapshots while archiving.
+func (m *Machine) OnArchiveProgress(fn func(archive.Progress)) {
+	m.mu.Lock()
+	m.progressFns = append(m.progressFns, fn)
+	m.mu.Unlock()
+}
+
+// ArchiveProgress returns the progress of the current (or last) archive run.
+func (m *Machine) ArchiveProgress() archive.Progress {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.progress
+}
+
+// ArchiveHistory returns past archive runs, newest first.
+func (m *Machine) ArchiveHistory() []ArchiveRun {
+	return m.history.List()
+}
+
+// ArchiveThroughput returns the recent archive throughput in bytes per
+// second, or 0 without history.
+func (m *Machine) ArchiveThroughput() float64 {
+	return m.history.Throughput(10)
+}
+
+// ArchiveRun returns the archive run with the given ID.
+func (m *Machine) ArchiveRun(id int) (ArchiveRun, bool) {
+	return m.history.Get(id)
+}
+
+// recordRun adds a finished archive attempt to the history.
+func (m *Machine) recordRun(start time.Time, res archive.Result, err error) {
+	run := ArchiveRun{Start: start, End: time.Now(), Status: "success", Result: res}
+	switch {
+	case errors.Is(err, errCancelled):
+		run.Error = err.Error()
+		run.Status = "cancelled"
+	case err != nil:
+		run.Error = err.Error()
+		run.Status = "failed"
+		if res.Clips > 0 {
+			run.Status = "partial"
+		}
+	}
+	if d := run.End.Sub(start).Seconds(); d > 0 {
+		run.BytesPerSec = float64(res.Bytes) / d
+	}
+	m.history.Append(run)
+}
+
+func (m *Machine) reportProgress(p archive.Progress) {
+	m.mu.Lock()
+	m.progress = p
+	fns := m.progressFns
+	m.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(p)
+	}
+}
+
 func (m *Machine) setState(s State) {
 	m.mu.Lock()
 	old := m.state
@@ -122,25 +269,55 @@ func (m *Machine) setState(s State) {
 // Run starts the main state machine loop.
 func (m *Machine) Run(ctx context.Context) error {
 	// First-run: create disk image if needed
+	extra := extraDrives()
 	if !disk.Exists() {
 		log.Println("first run: creating cam disk image...")
-		if err := disk.Create(); err != nil {
+		if err := disk.Create(extra); err != nil {
 			return fmt.Errorf("create disk: %w", err)
 		}
 	}
+	// Extra drives are optional; the car still gets the cam disk without them
+	if err := disk.CheckSpace(extra); err != nil {
+		log.Printf("extra drives: %v", err)
+	}
+	for _, img := range extra {
+		if !img.Exists() {
+			if err := img.Create(); err != nil {
+				log.Printf("create %s drive: %v", img.Name, err)
+			}
+			continue
+		}
+		// Repair the filesystem and restore missing folders before the car
+		// gets the drive
+		if err := img.MountLocal(); err != nil {
+			log.Printf("check %s drive: %v", img.Name, err)
+			continue
+		}
+		img.Unmount()
+	}
 
-	// Enable USB gadget (non-fatal — web UI should work even without UDC)
-	if err := gadget.Enable(disk.BackingFile); err != nil {
-		log.Printf("warning: %v (web UI still available, gadget will retry)", err)
+	// Finish an archive run cut short by power loss before the car gets
+	// the disk back
+	if m.recoverJournal() {
+		log.Println("archive server reachable, resuming interrupted archive")
 		m.mu.Lock()
-		m.lastError = err.Error()
+		m.presence.set(true, "resuming interrupted archive", time.Now())
 		m.mu.Unlock()
+		m.setState(StateArriving)
 	} else {
-		m.gadgetEnabled = true
-	}
+		// Enable USB gadget (non-fatal — web UI should work even without UDC)
+		if err := gadget.Enable(luns()...); err != nil {
+			log.Printf("warning: %v (web UI still available, gadget will retry)", err)
+			m.mu.Lock()
+			m.lastError = err.Error()
+			m.mu.Unlock()
+		} else {
+			m.gadgetEnabled = true
+		}
 
-	m.setState(StateAway)
-	system.SetLED("slowblink")
+		m.setState(StateAway)
+		system.SetLED("slowblink")
+	}
 
 	for {
 		select {
@@ -163,9 +340,31 @@ func (m *Machine) Run(ctx context.Context) error {
 	}
 }
 
+// extraDrives returns the configured extra drives.
+func extraDrives() []disk.Image {
+	if cfg := config.Get(); cfg != nil {
+		return disk.Extra(cfg.Drives)
+	}
+	return nil
+}
+
+// luns returns the drives to expose to the car: the cam disk, then each
+// configured extra drive whose image exists.
+func luns() []gadget.LUN {
+	luns := []gadget.LUN{{File: disk.Cam.File, Label: disk.Cam.Label}}
+	for _, img := range extraDrives() {
+		if img.Exists() {
+			luns = append(luns, gadget.LUN{File: img.File, Label: img.Label})
+		}
+	}
+	return luns
+}
+
 func (m *Machine) runAway(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
+	waiting := false
+	woken := false // Wake-on-LAN sent since joining the home network
 
 	for {
 		select {
@@ -174,17 +373,63 @@ func (m *Machine) runAway(ctx context.Context) {
 		case <-ticker.C:
 			// Retry gadget enable if it failed (e.g. UDC wasn't available at boot)
 			if !m.gadgetEnabled {
-				if err := gadget.Enable(disk.BackingFile); err == nil {
+				if err := gadget.Enable(luns()...); err == nil {
 					m.gadgetEnabled = true
 					log.Println("USB gadget enabled (delayed)")
 				}
 			}
-			if archive.IsReachable() {
+			p := m.checkPresence()
+			if !p.Home {
+				woken = false
+			}
+			target, ok := p.Target, p.Home && p.Reachable
+			if p.Home && !p.Reachable {
+				target, ok = m.wakeArchive(ctx, p, &woken)
+			}
+			if ok {
+				// Keep the gadget attached until archiving is allowed
+				if next, ok := archive.NextWindow(time.Now()); !ok || next.After(time.Now()) {
+					if !waiting {
+						log.Printf("archive target %s reachable, waiting for archive window (%s)", target, describeWindow(next, ok))
+						waiting = true
+					}
+					continue
+				}
 				m.setState(StateArriving)
 				return
 			}
+			waiting = false
+		}
+	}
+}
+
+// wakeArchive sends Wake-on-LAN packets to the archive server once the Pi
+// is home, then waits for it to come up. woken tracks whether the server was
+// already woken during this visit; when archive windows are set, waking
+// waits for one to open.
+func (m *Machine) wakeArchive(ctx context.Context, p Presence, woken *bool) (string, bool) {
+	cfg := config.Get()
+	if cfg == nil || cfg.Archive.WakeOnLAN.MAC == "" || *woken || !archive.InWindow(time.Now()) {
+		return "", false
+	}
+	*woken = true
+
+	mac := cfg.Archive.WakeOnLAN.MAC
+	log.Printf("home (%s), waking archive server %s", p.Reason, mac)
+	target, err := archive.Wake(ctx, cfg.Archive.WakeOnLAN)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false
 		}
+		log.Printf("wake archive server: %v", err)
+		notify.Send(ctx, webhook.Event{
+			Event:   "nas_wake_failed",
+			Message: err.Error(),
+			Data:    map[string]any{"mac": mac, "home": p.Reason},
+		})
+		return "", false
 	}
+	return target, true
 }
 
 func (m *Machine) runArriving(ctx context.Context) {
@@ -193,40 +438,133 @@ func (m *Machine) runArriving(ctx context.Context) {
 	log.Println("archive server reachable, waiting 20s for network to stabilize...")
 	time.Sleep(20 * time.Second)
 
+	if !archive.InWindow(time.Now()) {
+		log.Println("archive window closed, keeping USB gadget attached")
+		m.setState(StateAway)
+		return
+	}
+
 	system.SyncTime()
 
 	if err := gadget.WaitForIdle(); err != nil {
 		log.Printf("wait for idle: %v", err)
 	}
 
+	if j, err := archive.OpenJournal(archive.JournalFile); err != nil {
+		log.Printf("journal: %v", err)
+	} else {
+		m.journal = j
+	}
+
+	if cfg := config.Get(); cfg != nil && cfg.Archive.Snapshot {
+		m.snapshotCam()
+	}
+	if m.snapshot == nil && !m.detachCam(ctx) {
+		m.closeJournal()
+		m.setState(StateAway)
+		return
+	}
+
+	target, err := archive.MountArchive(ctx)
+	if err != nil {
+		log.Printf("mount archive: %v", err)
+		res := archive.Result{}
+		if cfg := config.Get(); cfg != nil && len(cfg.Targets) == 0 {
+			res.Backend = cfg.Archive.Method
+		}
+		m.recordRun(time.Now(), res, fmt.Errorf("mount archive: %w", err))
+		if m.snapshot != nil {
+			m.snapshot.Release()
+			m.snapshot = nil
+		} else {
+			disk.Unmount()
+			gadget.Enable(luns()...)
+		}
+		m.closeJournal()
+		m.setState(StateAway)
+		return
+	}
+	m.journal.SetPhase(archive.PhaseArchiveMounted)
+	log.Printf("archiving to target %s", target)
+	m.mu.Lock()
+	m.target = target
+	m.mu.Unlock()
+
+	m.setState(StateArchiving)
+}
+
+// detachCam takes the drive from the car: it disables the gadget and mounts
+// the cam disk. Returns false if either fails, with the gadget re-enabled
+// where possible.
+func (m *Machine) detachCam(ctx context.Context) bool {
 	if err := gadget.Disable(); err != nil {
 		log.Printf("disable gadget: %v", err)
 		m.gadgetEnabled = false
-		m.setState(StateAway)
-		return
+		return false
 	}
 	m.gadgetEnabled = false
+	m.journal.SetPhase(archive.PhaseGadgetDisabled)
 
 	notify.Send(ctx, webhook.Event{Event: "usb_disconnected", Message: "USB gadget disabled for archiving"})
 
 	if err := disk.Mount(); err != nil {
 		log.Printf("mount cam: %v", err)
-		gadget.Enable(disk.BackingFile)
-		m.setState(StateAway)
-		return
+		gadget.Enable(luns()...)
+		return false
 	}
+	m.journal.SetPhase(archive.PhaseCamMounted)
 
 	disk.CleanArtifacts()
+	return true
+}
 
-	if err := archive.MountArchive(); err != nil {
-		log.Printf("mount archive: %v", err)
-		disk.Unmount()
-		gadget.Enable(disk.BackingFile)
-		m.setState(StateAway)
+// snapshotCam takes a reflink snapshot of the cam disk to archive from
+// while the car keeps the drive. What to remove from the live image
+// afterwards comes from the journal, so without one the drive is
+// disconnected as usual.
+func (m *Machine) snapshotCam() {
+	if m.journal == nil {
+		log.Println("snapshot archiving needs the journal, disconnecting the drive instead")
+		return
+	}
+	snap, err := disk.TakeSnapshot("archive")
+	if err != nil {
+		log.Printf("snapshot: %v; disconnecting the drive instead", err)
 		return
 	}
+	m.snapshot = snap
+	m.journal.SetPhase(archive.PhaseSnapshotTaken)
+}
 
-	m.setState(StateArchiving)
+// finishSnapshot ends a snapshot run. It releases the snapshot, then
+// briefly takes the drive from the car to remove what the run archived or
+// filtered out. Returns whether the cam disk is now mounted; if the drive
+// can't be taken, the clips are left for the next run to send again.
+func (m *Machine) finishSnapshot(ctx context.Context) bool {
+	m.snapshot.Release()
+	m.snapshot = nil
+	m.journal.SetPhase(archive.PhaseSnapshotReleased)
+
+	st, err := archive.ReadJournal(archive.JournalFile)
+	if err != nil {
+		log.Printf("journal: %v", err)
+		return false
+	}
+	if st == nil || st.ToRemove() == 0 {
+		return false
+	}
+
+	log.Printf("disconnecting the drive to remove %d archived clips", st.ToRemove())
+	if err := gadget.WaitForIdle(); err != nil {
+		log.Printf("wait for idle: %v", err)
+	}
+	if !m.detachCam(ctx) {
+		return false
+	}
+	n, bytes := st.RemoveConfirmed(disk.MountPoint)
+	archive.CleanClipDirs(disk.MountPoint)
+	log.Printf("removed %d clips (%d MB) from the cam disk", n, bytes/(1024*1024))
+	return true
 }
 
 func (m *Machine) runArchiving(ctx context.Context) {
@@ -248,52 +586,136 @@ func (m *Machine) runArchiving(ctx context.Context) {
 		}
 	}()
 
-	notify.Send(ctx, webhook.Event{Event: "archive_started", Message: "Archiving dashcam clips"})
+	m.mu.RLock()
+	target := m.target
+	m.mu.RUnlock()
+	notify.Send(ctx, webhook.Event{
+		Event:   "archive_started",
+		Message: "Archiving dashcam clips to " + target,
+		Data:    map[string]any{"target": target},
+	})
+	runCtx, cancelRun := context.WithCancel(ctx)
+	gate := &archive.Gate{}
+	m.mu.Lock()
+	m.cancelRun, m.gate = cancelRun, gate
+	m.mu.Unlock()
+
 	start := time.Now()
-	clips, bytes, err := archive.ArchiveClips(ctx)
+	m.journal.SetPhase(archive.PhaseTransferring)
+	src := archive.LiveSource
+	if m.snapshot != nil {
+		src = archive.Source{Root: m.snapshot.Dir, Snapshot: true}
+	}
+	res, err := archive.ArchiveClips(runCtx, src, m.reportProgress, m.journal, gate)
 	duration := time.Since(start)
 
+	m.mu.Lock()
+	m.cancelRun, m.gate = nil, nil
+	m.mu.Unlock()
+	cancelled := err != nil && runCtx.Err() != nil && ctx.Err() == nil
+	cancelRun()
+	if cancelled {
+		err = errCancelled
+	}
+	if err == nil {
+		rep, rerr := archive.ApplyRetention(ctx)
+		if rerr != nil {
+			log.Printf("retention: %v", rerr)
+			rep = &archive.RetentionReport{Events: []archive.PrunedEvent{}, Error: rerr.Error()}
+		}
+		res.Retention = rep
+	}
+
 	keepAliveCancel()
+	m.recordRun(start, res, err)
 
-	if err != nil {
-		m.mu.Lock()
+	// Count whatever was archived, even if the run then failed
+	m.mu.Lock()
+	m.lastResult = res
+	m.cumulative.add(res)
+	switch {
+	case err == nil:
+		m.lastArchive = time.Now()
+		m.cumulative.ArchiveCount++
+		m.cumulative.LastArchive = m.lastArchive
+	case cancelled:
+		m.cumulative.Cancelled++
+	default:
 		m.lastError = err.Error()
-		m.mu.Unlock()
-		log.Printf("archive error: %v", err)
+	}
+	lastArchive := m.lastArchive
+	cumSnapshot := m.cumulative
+	m.mu.Unlock()
+	if statsData, err := json.Marshal(cumSnapshot); err == nil {
+		if err := os.WriteFile(statsFile, statsData, 0644); err != nil {
+			log.Printf("save stats: %v", err)
+		}
+	}
+
+	switch {
+	case cancelled:
+		log.Printf("archive cancelled after %d clips, %d left on the cam disk", res.Clips, res.Skipped)
 		notify.Send(ctx, webhook.Event{
+			Event:   "archive_cancelled",
+			Message: fmt.Sprintf("Archive to %s cancelled after %d clips; %d left on the cam disk", res.Target, res.Clips, res.Skipped),
+			Data: map[string]any{
+				"target":           res.Target,
+				"clips":            res.Clips,
+				"bytes":            res.Bytes,
+				"skipped":          res.Skipped,
+				"categories":       res.Categories,
+				"duration_seconds": int(duration.Seconds()),
+			},
+		})
+	case err != nil:
+		log.Printf("archive error: %v", err)
+		event := webhook.Event{
 			Event:   "archive_error",
 			Message: err.Error(),
-		})
-	} else {
-		now := time.Now()
-		m.mu.Lock()
-		m.lastArchive = now
-		m.archiveClips = clips
-		m.archiveBytes = bytes
-		m.cumulative.TotalClips += clips
-		m.cumulative.TotalBytes += bytes
-		m.cumulative.ArchiveCount++
-		m.cumulative.LastArchive = now
-		cumSnapshot := m.cumulative
-		m.mu.Unlock()
-		os.WriteFile(lastArchiveFile, []byte(now.Format(time.RFC3339)), 0644)
-		if statsData, err := json.Marshal(cumSnapshot); err == nil {
-			if err := os.WriteFile(statsFile, statsData, 0644); err != nil {
-				log.Printf("save stats: %v", err)
-			}
+		}
+		event.Data = map[string]any{
+			"target":     res.Target,
+			"clips":      res.Clips,
+			"bytes":      res.Bytes,
+			"skipped":    res.Skipped,
+			"failed":     res.Failed,
+			"categories": res.Categories,
+		}
+		if len(res.FailedFiles) > 0 {
+			event.Data["failed_files"] = res.FailedFiles
+		}
+		notify.Send(ctx, event)
+	default:
+		os.WriteFile(lastArchiveFile, []byte(lastArchive.Format(time.RFC3339)), 0644)
+		msg := fmt.Sprintf("Archived %d clips to %s in %s", res.Clips, res.Target, duration.Round(time.Second))
+		if summary := res.Summary(); summary != "" {
+			msg += ": " + summary
+		}
+		if pruned := res.Retention.Summary(); pruned != "" {
+			msg += "; " + pruned
 		}
 		notify.Send(ctx, webhook.Event{
 			Event:   "archive_complete",
-			Message: fmt.Sprintf("Archived %d clips in %s", clips, duration.Round(time.Second)),
+			Message: msg,
 			Data: map[string]any{
-				"clips":            clips,
-				"bytes":            bytes,
+				"target":           res.Target,
+				"clips":            res.Clips,
+				"bytes":            res.Bytes,
+				"events":           res.Events,
+				"skipped":          res.Skipped,
+				"failed":           res.Failed,
+				"categories":       res.Categories,
+				"archived_events":  res.ArchivedEvents,
+				"filtered":         res.Filtered,
+				"retention":        res.Retention,
 				"duration_seconds": int(duration.Seconds()),
 			},
 		})
 	}
 
-	archive.ManageFreeSpace()
+	if m.snapshot == nil || m.finishSnapshot(ctx) {
+		m.manageFreeSpace(ctx)
+	}
 	m.setState(StateIdle)
 }
 
@@ -306,13 +728,17 @@ func (m *Machine) runIdle(ctx context.Context) {
 	archive.UnmountArchive()
 	disk.Unmount()
 
-	if err := gadget.Enable(disk.BackingFile); err != nil {
-		log.Printf("warning: gadget re-enable failed: %v", err)
-		m.gadgetEnabled = false
-	} else {
-		m.gadgetEnabled = true
-		notify.Send(ctx, webhook.Event{Event: "usb_connected", Message: "USB gadget re-enabled"})
+	// A snapshot run may have left the drive with the car throughout
+	if !m.gadgetEnabled {
+		if err := gadget.Enable(luns()...); err != nil {
+			log.Printf("warning: gadget re-enable failed: %v", err)
+		} else {
+			m.gadgetEnabled = true
+			notify.Send(ctx, webhook.Event{Event: "usb_connected", Message: "USB gadget re-enabled"})
+		}
 	}
+	// The run is complete once the cam disk is released
+	m.closeJournal()
 
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -324,14 +750,14 @@ func (m *Machine) runIdle(ctx context.Context) {
 		case <-ticker.C:
 			// Retry gadget if it failed
 			if !m.gadgetEnabled {
-				if err := gadget.Enable(disk.BackingFile); err == nil {
+				if err := gadget.Enable(luns()...); err == nil {
 					m.gadgetEnabled = true
 					log.Println("USB gadget enabled (delayed)")
 					notify.Send(ctx, webhook.Event{Event: "usb_connected", Message: "USB gadget re-enabled"})
 				}
 			}
-			if !archive.IsReachable() {
-				log.Println("archive server unreachable — user left home")
+			if p := m.checkPresence(); !p.Home {
+				log.Printf("user left home (%s)", p.Reason)
 				m.setState(StateAway)
 				system.SetLED("slowblink")
 				return
@@ -340,6 +766,117 @@ func (m *Machine) runIdle(ctx context.Context) {
 	}
 }
 
+// checkPresence observes the network and archive targets and returns the
+// debounced presence.
+func (m *Machine) checkPresence() Presence {
+	var home config.Home
+	if cfg := config.Get(); cfg != nil {
+		home = cfg.Home
+	}
+	obs := observation{network: monitor.GetNetworkInfo()}
+	obs.target, obs.reachable = archive.IsReachable()
+
+	m.mu.Lock()
+	was := m.presence.Home
+	m.presence.update(obs, home, time.Now())
+	p := m.presence
+	m.mu.Unlock()
+	if p.Home && !was {
+		log.Printf("presence: home (%s)", p.Reason)
+	} else if !p.Home && was {
+		log.Printf("presence: away (%s)", p.Reason)
+	}
+	return p
+}
+
+// manageFreeSpace frees space on the cam disk and reports what was deleted.
+func (m *Machine) manageFreeSpace(ctx context.Context) {
+	report := archive.ManageFreeSpace()
+	if report == nil || len(report.Clips) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("Deleted %d clips (%d MB) from the cam disk to free space",
+		len(report.Clips), report.Freed/(1024*1024))
+	if report.Emergency {
+		msg += ", including unarchived clips (emergency)"
+	}
+	if short := report.Needed - report.Freed; short > 0 {
+		msg += fmt.Sprintf("; still %d MB short", short/(1024*1024))
+	}
+	notify.Send(ctx, webhook.Event{
+		Event:   "clips_pruned",
+		Message: msg,
+		Data: map[string]any{
+			"clips":     report.Clips,
+			"freed":     report.Freed,
+			"needed":    report.Needed,
+			"emergency": report.Emergency,
+		},
+	})
+}
+
+func describeWindow(next time.Time, ok bool) string {
+	if !ok {
+		return "no window configured opens"
+	}
+	return "opens " + next.Format("Mon 15:04")
+}
+
+func (m *Machine) closeJournal() {
+	m.journal.Close()
+	m.journal = nil
+}
+
+// recoverJournal cleans up after an archive run that was interrupted by a
+// crash or power loss: it releases stale mounts and loop devices, removes
+// clips the run had already confirmed archived, and records the run in the
+// history. Returns true if the archive is reachable and archiving allowed
+// now, so the run can be finished before the gadget is re-enabled.
+func (m *Machine) recoverJournal() bool {
+	st, err := archive.ReadJournal(archive.JournalFile)
+	if err != nil {
+		log.Printf("journal: %v", err)
+		return false
+	}
+	if st == nil {
+		return false
+	}
+	log.Printf("recovering archive run interrupted at %q (%d clips in flight, %d confirmed, %d dropped)",
+		st.Phase, len(st.InFlight), len(st.Confirmed), len(st.Dropped))
+	for _, key := range st.InFlight {
+		log.Printf("journal: %s was in flight, will be sent again", key)
+	}
+
+	archive.UnmountArchive()
+	disk.Unmount() // also detaches stale loop devices
+
+	// Snapshot runs also leave clips a filter dropped
+	if st.ToRemove() > 0 {
+		if err := disk.Mount(); err != nil {
+			log.Printf("journal: mount cam: %v", err)
+		} else {
+			n, _ := st.RemoveConfirmed(disk.MountPoint)
+			log.Printf("journal: removed %d archived clips left on the cam disk", n)
+			disk.Unmount()
+		}
+	}
+
+	var res archive.Result
+	res.Clips = len(st.Confirmed)
+	for _, size := range st.Confirmed {
+		res.Bytes += size
+	}
+	start := st.Time
+	if start.IsZero() {
+		start = time.Now()
+	}
+	m.recordRun(start, res, fmt.Errorf("interrupted during %s", st.Phase))
+	os.Remove(archive.JournalFile)
+
+	_, reachable := archive.IsReachable()
+	return reachable && archive.InWindow(time.Now())
+}
+
 func (m *Machine) sendKeepAwake(ctx context.Context, cfg *config.Config, command string) {
 	if cfg == nil {
 		return
@@ -2,6 +2,8 @@ package state
 
 import (
 	"testing"
+
+	"github.com/teslausb-go/teslausb/internal/archive"
 )
 
 func TestNewMachine(t *testing.T) {
@@ -28,3 +30,21 @@ func TestInfo(t *testing.T) {
 		t.Errorf("expected booting, got %s", info["state"])
 	}
 }
+
+func TestCumulativeStatsAdd(t *testing.T) {
+	var s CumulativeStats
+	res := archive.Result{
+		Tally: archive.Tally{Events: 2, Clips: 5, Bytes: 500, Failed: 1},
+		Categories: map[string]archive.Tally{
+			"SentryClips": {Events: 2, Clips: 5, Bytes: 500, Failed: 1},
+		},
+	}
+	s.add(res)
+	s.add(res)
+	if s.TotalClips != 10 || s.TotalBytes != 1000 || s.TotalEvents != 4 || s.TotalFailed != 2 {
+		t.Errorf("unexpected totals %+v", s)
+	}
+	if got := s.Categories["SentryClips"]; got.Clips != 10 || got.Events != 4 {
+		t.Errorf("unexpected SentryClips tally %+v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+	"github.com/teslausb-go/teslausb/internal/monitor"
+)
+
+// Default presence debounce, in 30-second checks: arriving needs a minute of
+// evidence, leaving two, so a NAS reboot or a WiFi blip doesn't look like a
+// departure.
+const (
+	defaultArriveChecks = 2
+	defaultLeaveChecks  = 4
+)
+
+// observation is what one presence check saw.
+type observation struct {
+	network   monitor.NetworkInfo
+	reachable bool
+	target    string // reachable archive target
+}
+
+// Presence explains why the Pi believes it is home or away.
+type Presence struct {
+	Home    bool      `json:"home"`
+	Since   time.Time `json:"since"`            // when Home last changed
+	Reason  string    `json:"reason"`           // evidence of the last check
+	Pending int       `json:"pending"`          // consecutive checks disagreeing with Home
+	Needed  int       `json:"needed"`           // checks needed before Home changes
+	Checked time.Time `json:"checked,omitzero"` // time of the last check
+
+	SSID       string `json:"ssid"`
+	Gateway    string `json:"gateway"`
+	GatewayMAC string `json:"gateway_mac"`
+	Reachable  bool   `json:"archive_reachable"`
+	Target     string `json:"archive_target,omitempty"`
+}
+
+// evidence reports whether one observation says the Pi is home, and why.
+// With the home network configured the SSID and gateway decide, so an
+// unreachable archive server doesn't count as leaving; otherwise archive
+// reachability does.
+func evidence(obs observation, home config.Home) (bool, string) {
+	if len(home.SSIDs) > 0 || len(home.Gateways) > 0 {
+		if match := obs.network.HomeMatch(home); match != "" {
+			return true, match
+		}
+		if obs.network.SSID == "" && obs.network.Gateway == "" {
+			return false, "not connected to a network"
+		}
+		return false, fmt.Sprintf("ssid %q and gateway %s are not home", obs.network.SSID, obs.network.Gateway)
+	}
+	if obs.reachable {
+		return true, "archive target " + obs.target + " reachable"
+	}
+	return false, "no archive target reachable"
+}
+
+// update records an observation at now and flips Home once enough
+// consecutive checks disagree with it: home.arrive_checks to arrive,
+// home.leave_checks to leave.
+func (p *Presence) update(obs observation, home config.Home, now time.Time) {
+	atHome, reason := evidence(obs, home)
+	p.Reason, p.Checked = reason, now
+	p.SSID, p.Gateway, p.GatewayMAC = obs.network.SSID, obs.network.Gateway, obs.network.GatewayMAC
+	p.Reachable, p.Target = obs.reachable, obs.target
+
+	p.Needed = home.LeaveChecks
+	if !p.Home {
+		p.Needed = home.ArriveChecks
+	}
+	if p.Needed <= 0 {
+		p.Needed = defaultLeaveChecks
+		if !p.Home {
+			p.Needed = defaultArriveChecks
+		}
+	}
+	if atHome == p.Home {
+		p.Pending = 0
+		return
+	}
+	p.Pending++
+	if p.Pending >= p.Needed {
+		p.set(atHome, reason, now)
+	}
+}
+
+// set changes Home immediately.
+func (p *Presence) set(home bool, reason string, now time.Time) {
+	if p.Home != home || p.Since.IsZero() {
+		p.Since = now
+	}
+	p.Home, p.Reason, p.Pending = home, reason, 0
+}
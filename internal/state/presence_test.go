@@ -0,0 +1,78 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/config"
+	"github.com/teslausb-go/teslausb/internal/monitor"
+)
+
+func TestPresenceDebounce(t *testing.T) {
+	home := config.Home{SSIDs: []string{"HomeWiFi"}}
+	atHome := observation{network: monitor.NetworkInfo{SSID: "HomeWiFi", Gateway: "192.168.1.1"}}
+	away := observation{network: monitor.NetworkInfo{SSID: "Cafe", Gateway: "10.0.0.1"}}
+	now := time.Now()
+	var p Presence
+	step := func(obs observation) {
+		now = now.Add(30 * time.Second)
+		p.update(obs, home, now)
+	}
+
+	step(atHome)
+	if p.Home || p.Pending != 1 || p.Needed != defaultArriveChecks {
+		t.Fatalf("after one home check: %+v", p)
+	}
+	step(away)
+	if p.Home || p.Pending != 0 {
+		t.Fatalf("away check should reset the arrival: %+v", p)
+	}
+	step(atHome)
+	step(atHome)
+	if !p.Home || p.Reason != `ssid "HomeWiFi"` || !p.Since.Equal(now) {
+		t.Fatalf("after two home checks: %+v", p)
+	}
+	arrived := now
+
+	// Leaving takes longer than arriving
+	for i := 1; i < defaultLeaveChecks; i++ {
+		step(away)
+		if !p.Home || p.Pending != i || p.Needed != defaultLeaveChecks {
+			t.Fatalf("after %d away checks: %+v", i, p)
+		}
+	}
+	if !p.Since.Equal(arrived) {
+		t.Errorf("since moved while home: %v", p.Since)
+	}
+	step(away)
+	if p.Home || p.Reason != `ssid "Cafe" and gateway 10.0.0.1 are not home` {
+		t.Fatalf("after %d away checks: %+v", defaultLeaveChecks, p)
+	}
+}
+
+func TestPresenceIgnoresArchiveWithHomeNetwork(t *testing.T) {
+	home := config.Home{Gateways: []string{"aa:bb:cc:dd:ee:ff"}, LeaveChecks: 1}
+	p := Presence{Home: true}
+	// The NAS rebooting doesn't make the Pi leave
+	p.update(observation{network: monitor.NetworkInfo{Gateway: "192.168.1.1", GatewayMAC: "AA:BB:CC:DD:EE:FF"}}, home, time.Now())
+	if !p.Home || p.Reachable || p.Reason != "gateway AA:BB:CC:DD:EE:FF" {
+		t.Errorf("unreachable NAS at home: %+v", p)
+	}
+	p.update(observation{reachable: true, target: "nas"}, home, time.Now())
+	if p.Home || p.Reason != "not connected to a network" {
+		t.Errorf("offline with a reachable target: %+v", p)
+	}
+}
+
+func TestPresenceFallsBackToReachability(t *testing.T) {
+	home := config.Home{ArriveChecks: 1, LeaveChecks: 1}
+	var p Presence
+	p.update(observation{network: monitor.NetworkInfo{SSID: "Anything"}, reachable: true, target: "nas"}, home, time.Now())
+	if !p.Home || p.Reason != "archive target nas reachable" || p.Target != "nas" {
+		t.Errorf("reachable without home network: %+v", p)
+	}
+	p.update(observation{network: monitor.NetworkInfo{SSID: "Anything"}}, home, time.Now())
+	if p.Home || p.Reason != "no archive target reachable" {
+		t.Errorf("unreachable without home network: %+v", p)
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/disk"
+)
+
+// camViewMaxAge is how long one snapshot serves the Files page. The next
+// request after that takes a fresh one, and an unused snapshot is released
+// so the car's writes don't keep growing its copy-on-write blocks.
+const camViewMaxAge = 2 * time.Minute
+
+var (
+	camMounted      = disk.Mounted
+	takeSnapshot    = disk.TakeSnapshot
+	releaseSnapshot = (*disk.Snapshot).Release
+)
+
+// camView hands out the directory the cam disk is read from: the disk
+// itself while it is mounted for archiving, otherwise a read-only snapshot
+// of the image the car is using.
+type camView struct {
+	mu  sync.Mutex
+	seq int
+	cur *viewSnapshot
+}
+
+type viewSnapshot struct {
+	snap  *disk.Snapshot
+	users int
+	stale bool        // replaced by a newer snapshot
+	timer *time.Timer // releases the snapshot once unused and old
+}
+
+// acquire returns the root to read the cam disk from and a func to call
+// once done reading.
+func (v *camView) acquire() (root string, release func(), err error) {
+	if camMounted() {
+		return disk.MountPoint, func() {}, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.cur == nil || time.Since(v.cur.snap.Taken) >= camViewMaxAge {
+		v.seq++
+		snap, err := takeSnapshot(fmt.Sprintf("browse-%d", v.seq))
+		if err != nil {
+			return "", nil, fmt.Errorf("cam disk is attached to the car and can't be snapshotted: %w", err)
+		}
+		if old := v.cur; old != nil {
+			old.stale = true
+			if old.users == 0 {
+				v.release(old)
+			}
+		}
+		v.cur = &viewSnapshot{snap: snap}
+	}
+	return v.cur.snap.Dir, v.use(v.cur), nil
+}
+
+// use adds a reader of vs and returns the func that ends it. It must be
+// called with v.mu held.
+func (v *camView) use(vs *viewSnapshot) func() {
+	vs.users++
+	if vs.timer != nil {
+		vs.timer.Stop()
+		vs.timer = nil
+	}
+	var once sync.Once
+	return func() { once.Do(func() { v.done(vs) }) }
+}
+
+// done ends one reader of vs, releasing it if it was replaced or
+// scheduling its release once it is too old to be handed out again.
+func (v *camView) done(vs *viewSnapshot) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	vs.users--
+	if vs.users > 0 {
+		return
+	}
+	if vs.stale {
+		v.release(vs)
+		return
+	}
+	vs.timer = time.AfterFunc(time.Until(vs.snap.Taken.Add(camViewMaxAge)), func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if vs.users == 0 && v.cur == vs {
+			v.cur = nil
+			v.release(vs)
+		}
+	})
+}
+
+// release must be called with v.mu held.
+func (v *camView) release(vs *viewSnapshot) {
+	if vs.timer != nil {
+		vs.timer.Stop()
+		vs.timer = nil
+	}
+	releaseSnapshot(vs.snap)
+}
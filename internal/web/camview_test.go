@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teslausb-go/teslausb/internal/disk"
+	"github.com/teslausb-go/teslausb/internal/state"
+)
+
+// fakeSnapshots replaces snapshotting with directories under a temp dir,
+// each holding TeslaCam/SavedClips/<name>.mp4.
+func fakeSnapshots(t *testing.T) (taken, released *[]string) {
+	taken, released = &[]string{}, &[]string{}
+	dir := t.TempDir()
+	oldMounted, oldTake, oldRelease := camMounted, takeSnapshot, releaseSnapshot
+	t.Cleanup(func() { camMounted, takeSnapshot, releaseSnapshot = oldMounted, oldTake, oldRelease })
+	camMounted = func() bool { return false }
+	takeSnapshot = func(name string) (*disk.Snapshot, error) {
+		s := &disk.Snapshot{Name: name, Dir: filepath.Join(dir, name), Taken: time.Now()}
+		os.MkdirAll(filepath.Join(s.Dir, "TeslaCam/SavedClips"), 0755)
+		os.WriteFile(filepath.Join(s.Dir, "TeslaCam/SavedClips", name+".mp4"), []byte(name), 0644)
+		*taken = append(*taken, name)
+		return s, nil
+	}
+	releaseSnapshot = func(s *disk.Snapshot) { *released = append(*released, s.Name) }
+	return taken, released
+}
+
+func TestCamViewSnapshots(t *testing.T) {
+	taken, released := fakeSnapshots(t)
+	v := &camView{}
+
+	root, done1, err := v.acquire()
+	if err != nil || filepath.Base(root) != "browse-1" {
+		t.Fatalf("acquire = %s %v", root, err)
+	}
+	root2, done2, _ := v.acquire()
+	if root2 != root || len(*taken) != 1 {
+		t.Errorf("expected the snapshot to be reused, took %v", *taken)
+	}
+	done2()
+
+	// An old snapshot is replaced, but kept until its last reader is done
+	v.cur.snap.Taken = time.Now().Add(-camViewMaxAge)
+	root3, done3, _ := v.acquire()
+	if filepath.Base(root3) != "browse-2" || len(*released) != 0 {
+		t.Fatalf("expected a new snapshot, got %s, released %v", root3, *released)
+	}
+	done1()
+	done1()
+	if len(*released) != 1 || (*released)[0] != "browse-1" {
+		t.Errorf("expected browse-1 released once, got %v", *released)
+	}
+
+	// The current snapshot goes once it is too old to hand out
+	v.cur.snap.Taken = time.Now().Add(-camViewMaxAge)
+	done3()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		v.mu.Lock()
+		n := len(*released)
+		v.mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(*released) != 2 || v.cur != nil {
+		t.Errorf("expected browse-2 released after use, got %v", *released)
+	}
+}
+
+func TestCamViewMounted(t *testing.T) {
+	taken, _ := fakeSnapshots(t)
+	camMounted = func() bool { return true }
+	root, done, err := (&camView{}).acquire()
+	done()
+	if err != nil || root != disk.MountPoint || len(*taken) != 0 {
+		t.Errorf("acquire while mounted = %s %v, took %v", root, err, *taken)
+	}
+}
+
+func TestFilesFromSnapshot(t *testing.T) {
+	fakeSnapshots(t)
+	s := NewServer(state.New(), "test", "/tmp/test.yaml")
+
+	w := httptest.NewRecorder()
+	s.handleListFiles(w, httptest.NewRequest("GET", "/api/files?path=TeslaCam/SavedClips", nil))
+	var files []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+	json.NewDecoder(w.Body).Decode(&files)
+	if len(files) != 1 || files[0].Name != "browse-1.mp4" {
+		t.Fatalf("expected the snapshot's clip, got %d %+v", w.Code, files)
+	}
+
+	w = httptest.NewRecorder()
+	s.handleDownloadFile(w, httptest.NewRequest("GET", "/api/files/download?path="+files[0].Path, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "browse-1" {
+		t.Errorf("download = %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.handleDeleteFile(w, httptest.NewRequest("POST", "/api/files/delete", strings.NewReader(`{"path":"`+files[0].Path+`"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting from a snapshot, got %d", w.Code)
+	}
+}
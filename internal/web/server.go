@@ -1,8 +1,11 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
@@ -10,10 +13,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/teslausb-go/teslausb/internal/archive"
 	"github.com/teslausb-go/teslausb/internal/ble"
 	"github.com/teslausb-go/teslausb/internal/config"
 	"github.com/teslausb-go/teslausb/internal/disk"
@@ -28,6 +34,7 @@ type Server struct {
 	hub      *Hub
 	cfgPath  string
 	staticFS fs.FS
+	cam      *camView
 }
 
 func NewServer(machine *state.Machine, version, cfgPath string) *Server {
@@ -36,6 +43,7 @@ func NewServer(machine *state.Machine, version, cfgPath string) *Server {
 		version: version,
 		hub:     NewHub(),
 		cfgPath: cfgPath,
+		cam:     &camView{},
 	}
 }
 
@@ -51,11 +59,21 @@ func (s *Server) Start(addr string) error {
 	mux.HandleFunc("GET /api/files", s.handleListFiles)
 	mux.HandleFunc("GET /api/files/download", s.handleDownloadFile)
 	mux.HandleFunc("POST /api/files/delete", s.handleDeleteFile)
+	mux.HandleFunc("GET /api/files/protected", s.handleProtectedEvents)
+	mux.HandleFunc("POST /api/files/protect", s.handleProtectEvent)
 	mux.HandleFunc("GET /api/config", s.handleGetConfig)
 	mux.HandleFunc("POST /api/config", s.handleSaveConfig)
 	mux.HandleFunc("POST /api/nfs/test", s.handleTestNFS)
 	mux.HandleFunc("POST /api/cifs/test", s.handleTestCIFS)
+	mux.HandleFunc("GET /api/sftp/key", s.handleSFTPKey)
 	mux.HandleFunc("POST /api/archive/trigger", s.handleTriggerArchive)
+	mux.HandleFunc("POST /api/archive/cancel", s.handleCancelArchive)
+	mux.HandleFunc("POST /api/archive/pause", s.handlePauseArchive)
+	mux.HandleFunc("POST /api/archive/resume", s.handleResumeArchive)
+	mux.HandleFunc("POST /api/archive/plan", s.handleArchivePlan)
+	mux.HandleFunc("GET /api/archive/progress", s.handleArchiveProgress)
+	mux.HandleFunc("GET /api/archive/history", s.handleArchiveHistory)
+	mux.HandleFunc("GET /api/archive/history/{id}", s.handleArchiveRun)
 	mux.HandleFunc("POST /api/ble/pair", s.handleBLEPair)
 	mux.HandleFunc("GET /api/ble/status", s.handleBLEStatus)
 	mux.HandleFunc("GET /api/logs", s.handleLogs)
@@ -74,11 +92,20 @@ func (s *Server) Start(addr string) error {
 	s.machine.OnStateChange(func(st state.State) {
 		s.hub.Broadcast(map[string]any{"type": "state", "state": string(st)})
 	})
+	s.machine.OnArchiveProgress(func(p archive.Progress) {
+		s.hub.Broadcast(progressMessage{Type: "archive_progress", Progress: p})
+	})
 
 	log.Printf("web server starting on %s", addr)
 	return http.ListenAndServe(addr, mux)
 }
 
+// progressMessage is the WebSocket payload for archive progress updates.
+type progressMessage struct {
+	Type string `json:"type"`
+	archive.Progress
+}
+
 func jsonResponse(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -95,6 +122,19 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	info["wifi_signal_dbm"] = net.SignalDBM
 	info["wifi_ip"] = net.IP
 
+	// Outside archiving the Files page reads a snapshot
+	info["cam_disk_mounted"] = camMounted()
+
+	// Archive window
+	now := time.Now()
+	if next, ok := archive.NextWindow(now); ok {
+		info["archive_window_open"] = !next.After(now)
+		info["next_archive_window"] = next
+	} else {
+		info["archive_window_open"] = false
+		info["next_archive_window"] = nil
+	}
+
 	// Disk usage
 	var stat syscall.Statfs_t
 	if err := syscall.Statfs(disk.MountPoint, &stat); err == nil {
@@ -118,7 +158,13 @@ func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid path", 400)
 		return
 	}
-	fullPath := filepath.Join(disk.MountPoint, reqPath)
+	root, release, err := s.cam.acquire()
+	if err != nil {
+		http.Error(w, err.Error(), 503)
+		return
+	}
+	defer release()
+	fullPath := filepath.Join(root, reqPath)
 	entries, err := os.ReadDir(fullPath)
 	if err != nil {
 		jsonResponse(w, []any{})
@@ -126,11 +172,13 @@ func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type fileInfo struct {
-		Name  string `json:"name"`
-		IsDir bool   `json:"is_dir"`
-		Size  int64  `json:"size"`
-		Path  string `json:"path"`
+		Name      string `json:"name"`
+		IsDir     bool   `json:"is_dir"`
+		Size      int64  `json:"size"`
+		Path      string `json:"path"`
+		Protected bool   `json:"protected"`
 	}
+	protected, _ := archive.ProtectedEvents()
 	files := make([]fileInfo, 0)
 	for _, e := range entries {
 		info, _ := e.Info()
@@ -138,11 +186,13 @@ func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
 		if info != nil {
 			size = info.Size()
 		}
+		p := filepath.Join(reqPath, e.Name())
 		files = append(files, fileInfo{
-			Name:  e.Name(),
-			IsDir: e.IsDir(),
-			Size:  size,
-			Path:  filepath.Join(reqPath, e.Name()),
+			Name:      e.Name(),
+			IsDir:     e.IsDir(),
+			Size:      size,
+			Path:      p,
+			Protected: slices.Contains(protected, filepath.ToSlash(p)),
 		})
 	}
 	jsonResponse(w, files)
@@ -154,8 +204,13 @@ func (s *Server) handleDownloadFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid path", 400)
 		return
 	}
-	fullPath := filepath.Join(disk.MountPoint, reqPath)
-	http.ServeFile(w, r, fullPath)
+	root, release, err := s.cam.acquire()
+	if err != nil {
+		http.Error(w, err.Error(), 503)
+		return
+	}
+	defer release()
+	http.ServeFile(w, r, filepath.Join(root, reqPath))
 }
 
 func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
@@ -168,6 +223,12 @@ func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid path", 400)
 		return
 	}
+	// Outside archiving the car owns the disk; the Files page only sees a
+	// read-only snapshot of it
+	if !camMounted() {
+		http.Error(w, "the cam disk is attached to the car and read-only", 409)
+		return
+	}
 	fullPath := filepath.Join(disk.MountPoint, req.Path)
 	if err := os.RemoveAll(fullPath); err != nil {
 		http.Error(w, err.Error(), 500)
@@ -176,6 +237,35 @@ func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]string{"status": "ok"})
 }
 
+func (s *Server) handleProtectedEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := archive.ProtectedEvents()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	jsonResponse(w, events)
+}
+
+// handleProtectEvent stars or unstars an event folder so free-space
+// management never deletes it.
+func (s *Server) handleProtectEvent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path      string `json:"path"`
+		Protected bool   `json:"protected"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	req.Path = filepath.ToSlash(filepath.Clean(req.Path))
+	if strings.Contains(req.Path, "..") || !strings.HasPrefix(req.Path, "TeslaCam/") {
+		http.Error(w, "invalid path", 400)
+		return
+	}
+	if err := archive.SetProtected(req.Path, req.Protected); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	cfg := config.Get()
 	if cfg == nil {
@@ -184,12 +274,26 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, cfg)
 }
 
+// checkDriveSpace is replaced in tests.
+var checkDriveSpace = disk.CheckSpace
+
 func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	var cfg config.Config
 	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
+	if err := archive.Validate(&cfg); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	// New drives must fit next to the cam disk image
+	if cur := config.Get(); cur == nil || cfg.Drives != cur.Drives {
+		if err := checkDriveSpace(disk.Extra(cfg.Drives)); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
 	if err := config.Save(s.cfgPath, &cfg); err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -277,6 +381,15 @@ func (s *Server) handleTestCIFS(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]any{"ok": true, "message": fmt.Sprintf("Successfully mounted %s", source)})
 }
 
+func (s *Server) handleSFTPKey(w http.ResponseWriter, r *http.Request) {
+	key, err := archive.SFTPPublicKey()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	jsonResponse(w, map[string]string{"public_key": key})
+}
+
 func (s *Server) handleTriggerArchive(w http.ResponseWriter, r *http.Request) {
 	if s.machine.TriggerArchive() {
 		jsonResponse(w, map[string]string{"status": "triggered"})
@@ -285,6 +398,96 @@ func (s *Server) handleTriggerArchive(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleCancelArchive(w http.ResponseWriter, r *http.Request) {
+	if s.machine.CancelArchive() {
+		jsonResponse(w, map[string]string{"status": "cancelling"})
+	} else {
+		jsonResponse(w, map[string]string{"status": "not_archiving", "error": "no archive is running"})
+	}
+}
+
+func (s *Server) handlePauseArchive(w http.ResponseWriter, r *http.Request) {
+	if s.machine.PauseArchive() {
+		jsonResponse(w, map[string]string{"status": "paused"})
+	} else {
+		jsonResponse(w, map[string]string{"status": "not_running", "error": "no running archive to pause"})
+	}
+}
+
+func (s *Server) handleResumeArchive(w http.ResponseWriter, r *http.Request) {
+	if s.machine.ResumeArchive() {
+		jsonResponse(w, map[string]string{"status": "resumed"})
+	} else {
+		jsonResponse(w, map[string]string{"status": "not_paused", "error": "no paused archive to resume"})
+	}
+}
+
+// handleArchivePlan reports what the next archive run would do with the clips
+// on the cam disk. An optional config in the body is planned with instead of
+// the saved one, to preview filter changes before saving them.
+func (s *Server) handleArchivePlan(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		cfg = &config.Config{}
+		if err := json.Unmarshal(body, cfg); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		// Only what selection uses; an unsaved config may lack a target
+		if err := errors.Join(archive.ValidateTemplate(cfg.Archive.PathTemplate), archive.ValidateFilters(cfg.Archive.Filters)); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+	// The cam disk is only mounted while the car can't see it; otherwise
+	// plan on a snapshot, shared with the Files page
+	root, release, err := s.cam.acquire()
+	if err != nil {
+		http.Error(w, err.Error(), 503)
+		return
+	}
+	defer release()
+	plan := archive.PlanArchive(root, cfg, time.Now())
+	plan.Estimate(s.machine.ArchiveThroughput())
+	jsonResponse(w, plan)
+}
+
+func (s *Server) handleArchiveProgress(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, s.machine.ArchiveProgress())
+}
+
+// handleArchiveHistory lists past runs, newest first, without the per-event
+// details; ?limit=N returns only the newest N.
+func (s *Server) handleArchiveHistory(w http.ResponseWriter, r *http.Request) {
+	runs := s.machine.ArchiveHistory()
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(runs) {
+		runs = runs[:limit]
+	}
+	for i := range runs {
+		runs[i].ArchivedEvents = nil
+	}
+	jsonResponse(w, runs)
+}
+
+func (s *Server) handleArchiveRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid run id", 400)
+		return
+	}
+	run, ok := s.machine.ArchiveRun(id)
+	if !ok {
+		http.Error(w, "run not found", 404)
+		return
+	}
+	jsonResponse(w, run)
+}
+
 func (s *Server) handleBLEPair(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		VIN string `json:"vin"`
@@ -2,10 +2,17 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/teslausb-go/teslausb/internal/archive"
+	"github.com/teslausb-go/teslausb/internal/disk"
 	"github.com/teslausb-go/teslausb/internal/state"
 )
 
@@ -57,3 +64,154 @@ func TestBLEStatusEndpoint(t *testing.T) {
 		t.Errorf("expected keys_exist=false")
 	}
 }
+
+func TestArchiveProgressEndpoint(t *testing.T) {
+	m := state.New()
+	s := NewServer(m, "test", "/tmp/test.yaml")
+
+	req := httptest.NewRequest("GET", "/api/archive/progress", nil)
+	w := httptest.NewRecorder()
+	s.handleArchiveProgress(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	var result map[string]any
+	json.NewDecoder(w.Body).Decode(&result)
+	if result["running"] != false {
+		t.Errorf("expected running=false, got %v", result["running"])
+	}
+}
+
+func TestArchiveHistoryEndpoints(t *testing.T) {
+	s := NewServer(state.New(), "test", "/tmp/test.yaml")
+
+	w := httptest.NewRecorder()
+	s.handleArchiveHistory(w, httptest.NewRequest("GET", "/api/archive/history?limit=5", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/archive/history/abc", nil)
+	req.SetPathValue("id", "abc")
+	w = httptest.NewRecorder()
+	s.handleArchiveRun(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid id, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/archive/history/999999", nil)
+	req.SetPathValue("id", "999999")
+	w = httptest.NewRecorder()
+	s.handleArchiveRun(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestArchivePlanEndpoint(t *testing.T) {
+	taken, _ := fakeSnapshots(t)
+	s := NewServer(state.New(), "test", "/tmp/test.yaml")
+
+	// While the car has the cam disk, the plan reads a snapshot of it
+	w := httptest.NewRecorder()
+	s.handleArchivePlan(w, httptest.NewRequest("POST", "/api/archive/plan", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var plan archive.Plan
+	if err := json.NewDecoder(w.Body).Decode(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(*taken) != 1 || len(plan.Clips) != 1 || plan.Clips[0].Path != "TeslaCam/SavedClips/browse-1.mp4" {
+		t.Fatalf("expected the snapshot's clip planned, took %v, got %+v", *taken, plan.Clips)
+	}
+	if got := plan.Totals[archive.FilterArchiveDelete]; got.Clips != 1 || got.Bytes != int64(len("browse-1")) {
+		t.Errorf("archive totals = %+v", got)
+	}
+
+	// Filters sent with the request decide a populated cam disk
+	take := takeSnapshot
+	takeSnapshot = func(name string) (*disk.Snapshot, error) {
+		snap, err := take(name)
+		if err == nil {
+			event := filepath.Join(snap.Dir, "TeslaCam/SentryClips/2024-05-01_18-22-10")
+			os.MkdirAll(event, 0755)
+			os.WriteFile(filepath.Join(event, "2024-05-01_18-12-10-front.mp4"), make([]byte, 100), 0644)
+			os.WriteFile(filepath.Join(event, "2024-05-01_18-12-10-back.mp4"), make([]byte, 200), 0644)
+			os.WriteFile(filepath.Join(event, "2024-05-01_18-12-10-left_repeater.mp4"), make([]byte, 300), 0644)
+		}
+		return snap, err
+	}
+	s.cam.cur.snap.Taken = time.Now().Add(-camViewMaxAge)
+	body := strings.NewReader(`{"archive":{"path_template":"{category}/{event}","filters":[
+		{"categories":["SentryClips"],"cameras":["back"],"action":"delete"},
+		{"categories":["SentryClips"],"cameras":["left_repeater"],"action":"archive"},
+		{"categories":["SavedClips"],"action":"skip"}]}}`)
+	w = httptest.NewRecorder()
+	s.handleArchivePlan(w, httptest.NewRequest("POST", "/api/archive/plan", body))
+	plan = archive.Plan{}
+	if err := json.NewDecoder(w.Body).Decode(&plan); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("plan = %d %v", w.Code, err)
+	}
+	for action, want := range map[string]archive.PlanTotal{
+		archive.FilterArchiveDelete: {Clips: 1, Bytes: 100},
+		archive.FilterArchive:       {Clips: 1, Bytes: 300},
+		archive.FilterSkip:          {Clips: 1, Bytes: int64(len("browse-2"))},
+		archive.FilterDelete:        {Clips: 1, Bytes: 200},
+	} {
+		if got := plan.Totals[action]; got != want {
+			t.Errorf("%s totals = %+v, want %+v", action, got, want)
+		}
+	}
+	if len(plan.Events) != 2 || len(plan.Retention) != 0 || len(plan.FreeSpace) != 0 {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+
+	body = strings.NewReader(`{"archive":{"filters":[{"action":"shred"}]}}`)
+	w = httptest.NewRecorder()
+	s.handleArchivePlan(w, httptest.NewRequest("POST", "/api/archive/plan", body))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid filter, got %d", w.Code)
+	}
+}
+
+func TestArchiveControlEndpoints(t *testing.T) {
+	s := NewServer(state.New(), "test", "/tmp/test.yaml")
+	for path, handler := range map[string]http.HandlerFunc{
+		"/api/archive/cancel": s.handleCancelArchive,
+		"/api/archive/pause":  s.handlePauseArchive,
+		"/api/archive/resume": s.handleResumeArchive,
+	} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", path, nil))
+		var result map[string]string
+		json.NewDecoder(w.Body).Decode(&result)
+		if w.Code != http.StatusOK || result["error"] == "" {
+			t.Errorf("%s: expected an error without a running archive, got %d %v", path, w.Code, result)
+		}
+	}
+}
+
+func TestSaveConfigDriveSpace(t *testing.T) {
+	orig := checkDriveSpace
+	t.Cleanup(func() { checkDriveSpace = orig })
+	checkDriveSpace = func(extra []disk.Image) error {
+		if len(extra) > 0 {
+			return errors.New("no room for the music drive")
+		}
+		return nil
+	}
+	s := NewServer(state.New(), "test", filepath.Join(t.TempDir(), "config.yaml"))
+
+	w := httptest.NewRecorder()
+	s.handleSaveConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(`{"drives":{"music_gb":8}}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "no room") {
+		t.Errorf("expected 400 for a drive that doesn't fit, got %d %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	s.handleSaveConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 without drives, got %d %s", w.Code, w.Body)
+	}
+}
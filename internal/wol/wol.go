@@ -0,0 +1,43 @@
+// Package wol sends Wake-on-LAN magic packets.
+package wol
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// MagicPacket builds the payload that wakes the machine with the given MAC
+// address: six 0xFF bytes followed by the address sixteen times.
+func MagicPacket(mac string) ([]byte, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	if len(hw) != 6 {
+		return nil, fmt.Errorf("wake-on-lan needs a 6-byte MAC address, got %q", mac)
+	}
+	packet := bytes.Repeat([]byte{0xFF}, 6)
+	for range 16 {
+		packet = append(packet, hw...)
+	}
+	return packet, nil
+}
+
+// Send sends a magic packet for mac to the UDP broadcast address and port.
+func Send(mac, broadcast string, port int) error {
+	packet, err := MagicPacket(mac)
+	if err != nil {
+		return err
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(broadcast, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("wake-on-lan: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(packet); err != nil {
+		return fmt.Errorf("wake-on-lan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package wol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMagicPacket(t *testing.T) {
+	p, err := MagicPacket("00:11:22:aa:bb:cc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 102 {
+		t.Fatalf("len = %d, want 102", len(p))
+	}
+	if !bytes.Equal(p[:6], bytes.Repeat([]byte{0xFF}, 6)) {
+		t.Errorf("header = %x", p[:6])
+	}
+	mac := []byte{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc}
+	for i := range 16 {
+		if got := p[6+i*6 : 12+i*6]; !bytes.Equal(got, mac) {
+			t.Errorf("repetition %d = %x", i, got)
+		}
+	}
+
+	for _, bad := range []string{"", "not-a-mac", "00:00:5e:00:53:01:02:03"} {
+		if _, err := MagicPacket(bad); err == nil {
+			t.Errorf("MagicPacket(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	if err := Send("00-11-22-AA-BB-CC", "127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 256)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := MagicPacket("00:11:22:aa:bb:cc")
+	if !bytes.Equal(buf[:n], want) {
+		t.Errorf("received %x", buf[:n])
+	}
+}
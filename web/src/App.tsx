@@ -3,6 +3,7 @@ import { Layout } from './components/Layout';
 import { Dashboard } from './pages/Dashboard';
 import { Config } from './pages/Config';
 import { Logs } from './pages/Logs';
+import { History } from './pages/History';
 
 function App() {
   const [tab, setTab] = useState('dashboard');
@@ -10,6 +11,7 @@ function App() {
   return (
     <Layout activeTab={tab} onTabChange={setTab}>
       {tab === 'dashboard' && <Dashboard />}
+      {tab === 'history' && <History />}
       {tab === 'config' && <Config />}
       {tab === 'logs' && <Logs />}
     </Layout>
@@ -2,6 +2,7 @@ import type { ReactNode } from 'react';
 
 const tabs = [
   { id: 'dashboard', label: 'Dashboard' },
+  { id: 'history', label: 'History' },
   { id: 'config', label: 'Config' },
   { id: 'logs', label: 'Logs' },
 ];
@@ -17,12 +17,182 @@ export interface Status {
   last_error: string;
   archive_clips: number;
   archive_bytes: number;
+  archive_result?: ArchiveResult;
+  archive_target: string;
   total_archive_clips: number;
   total_archive_bytes: number;
   archive_count: number;
+  archive_window_open: boolean;
+  next_archive_window: string | null;
   wifi_ssid: string;
   wifi_signal_dbm: number;
   wifi_ip: string;
+  presence?: Presence;
+  cam_disk_mounted: boolean;
+}
+
+export interface Presence {
+  home: boolean;
+  since: string;
+  reason: string;
+  pending: number;
+  needed: number;
+  checked?: string;
+  ssid: string;
+  gateway: string;
+  gateway_mac: string;
+  archive_reachable: boolean;
+  archive_target?: string;
+}
+
+export interface ArchiveWindow {
+  days: string[];
+  start: string;
+  end: string;
+}
+
+export interface ArchiveTarget {
+  name: string;
+  method: string;
+  nfs: Config['nfs'];
+  cifs: Config['cifs'];
+  s3: Config['s3'];
+  sftp: Config['sftp'];
+  webdav: Config['webdav'];
+}
+
+export interface FilterRule {
+  categories: string[] | null;
+  cameras: string[] | null;
+  reasons: string[] | null;
+  min_age_days: number;
+  max_age_days: number;
+  action: 'archive' | 'archive_delete' | 'skip' | 'delete';
+}
+
+export interface FilterOutcome {
+  rule: number;
+  action: string;
+  clips: number;
+  bytes: number;
+}
+
+export interface PlannedClip {
+  path: string;
+  dest?: string;
+  size: number;
+  action: 'archive' | 'archive_delete' | 'skip' | 'delete';
+  rule?: number;
+}
+
+export interface PlannedEvent {
+  path: string;
+  category: string;
+  time: string;
+  reason?: string;
+  city?: string;
+  actions: Record<string, number>;
+  bytes: number;
+}
+
+export interface ArchivePlan {
+  events: PlannedEvent[];
+  clips: PlannedClip[];
+  totals: Record<string, {clips: number; bytes: number}>;
+  retention: PrunedEvent[];
+  free_space: FreedClip[];
+  bytes_per_sec: number;
+  estimated_seconds: number;
+}
+
+export interface FreedClip {
+  path: string;
+  size: number;
+  reason: 'recent_clips' | 'emergency';
+}
+
+export interface RetentionRule {
+  category: string;
+  max_age_days: number;
+  max_size_gb: number;
+}
+
+export interface PrunedEvent {
+  path: string;
+  category: string;
+  time: string;
+  files: number;
+  bytes: number;
+  reason: 'age' | 'quota';
+}
+
+export interface RetentionReport {
+  dry_run: boolean;
+  events: PrunedEvent[];
+  bytes: number;
+  error?: string;
+}
+
+export interface ArchiveTally {
+  events: number;
+  clips: number;
+  bytes: number;
+  skipped: number;
+  failed: number;
+  reasons?: Record<string, number>;
+}
+
+export interface ArchiveEvent {
+  dir: string;
+  category: string;
+  timestamp: string;
+  city?: string;
+  lat?: number;
+  lon?: number;
+  reason?: string;
+  camera?: string;
+  has_thumb: boolean;
+}
+
+export interface FileError {
+  path: string;
+  error: string;
+}
+
+export interface ArchiveResult extends ArchiveTally {
+  target?: string;
+  snapshot?: boolean;
+  categories: Record<string, ArchiveTally> | null;
+  archived_events?: ArchiveEvent[];
+  failed_files?: FileError[];
+  retention?: RetentionReport;
+  filtered?: FilterOutcome[];
+}
+
+export interface ArchiveRun extends ArchiveResult {
+  id: number;
+  start: string;
+  end: string;
+  status: 'success' | 'partial' | 'failed';
+  error?: string;
+  bytes_per_sec: number;
+  backend?: string;
+  destination?: string;
+}
+
+export interface ArchiveProgress {
+  running: boolean;
+  files_done: number;
+  files_failed: number;
+  files_total: number;
+  bytes_done: number;
+  bytes_total: number;
+  current_file: string;
+  active_files?: string[];
+  bytes_per_sec: number;
+  eta_seconds: number;
+  started_at: string;
+  paused: boolean;
 }
 
 export interface FileEntry {
@@ -30,15 +200,51 @@ export interface FileEntry {
   is_dir: boolean;
   size: number;
   path: string;
+  protected: boolean;
+}
+
+export interface WakeOnLAN {
+  mac: string;
+  broadcast: string;
+  port: number;
+  timeout_seconds: number;
 }
 
 export interface Config {
   nfs: { server: string; share: string };
   cifs: { server: string; share: string; username: string; password: string };
-  archive: { recent_clips: boolean; reserve_percent: number; method: string };
+  s3: {
+    endpoint: string; region: string; bucket: string; prefix: string;
+    access_key: string; secret_key: string; path_style: boolean;
+  };
+  sftp: { server: string; port: number; username: string; path: string; host_key: string };
+  webdav: { url: string; username: string; password: string; token: string };
+  archive: {
+    recent_clips: boolean;
+    reserve_percent: number;
+    emergency_free_percent: number;
+    method: string;
+    verify: string;
+    engine: string;
+    snapshot: boolean;
+    path_template: string;
+    bandwidth_limit_kb: number;
+    workers: number;
+    windows: ArchiveWindow[] | null;
+    retention: RetentionRule[] | null;
+    retention_dry_run: boolean;
+    filters: FilterRule[] | null;
+    wake_on_lan: WakeOnLAN;
+  };
+  home: {
+    ssids: string[] | null; gateways: string[] | null;
+    arrive_checks: number; leave_checks: number;
+  };
+  drives: { music_gb: number; lightshow_gb: number; boombox_gb: number };
   keep_awake: { method: string; vin: string; webhook_url: string };
   notifications: { webhook_url: string };
   temperature: { warning_celsius: number; caution_celsius: number };
+  targets: ArchiveTarget[] | null;
 }
 
 export interface BLEStatus {
@@ -73,12 +279,29 @@ export const api = {
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ server, share, username, password }),
     }),
+  protectEvent: (path: string, isProtected: boolean) => fetchJSON<{status: string}>('/api/files/protect', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ path, protected: isProtected }),
+  }),
   getConfig: () => fetchJSON<Config>('/api/config'),
   saveConfig: (config: Config) => fetchJSON<{status: string}>('/api/config', {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify(config),
   }),
+  getSFTPKey: () => fetchJSON<{public_key: string}>('/api/sftp/key'),
+  getArchiveProgress: () => fetchJSON<ArchiveProgress>('/api/archive/progress'),
+  getArchiveHistory: (limit = 100) => fetchJSON<ArchiveRun[]>(`/api/archive/history?limit=${limit}`),
+  getArchiveRun: (id: number) => fetchJSON<ArchiveRun>(`/api/archive/history/${id}`),
+  planArchive: (config?: Config) => fetchJSON<ArchivePlan>('/api/archive/plan', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: config ? JSON.stringify(config) : '',
+  }),
+  cancelArchive: () => fetchJSON<{status: string}>('/api/archive/cancel', { method: 'POST' }),
+  pauseArchive: () => fetchJSON<{status: string}>('/api/archive/pause', { method: 'POST' }),
+  resumeArchive: () => fetchJSON<{status: string}>('/api/archive/resume', { method: 'POST' }),
   triggerArchive: () => fetchJSON<{status: string}>('/api/archive/trigger', { method: 'POST' }),
   pairBLE: (vin: string) => fetchJSON<{status: string}>('/api/ble/pair', {
     method: 'POST',
@@ -1,6 +1,7 @@
 import { useEffect, useState } from 'react';
 import { api } from '../lib/api';
-import type { Config as ConfigType } from '../lib/api';
+import { formatBytes } from '../lib/format';
+import type { ArchivePlan, ArchiveTarget, ArchiveWindow, Config as ConfigType, FilterRule, RetentionRule, WakeOnLAN } from '../lib/api';
 
 export function Config() {
   const [config, setConfig] = useState<ConfigType | null>(null);
@@ -8,6 +9,10 @@ export function Config() {
   const [message, setMessage] = useState('');
 
   const [bleStatus, setBleStatus] = useState<{keys_exist: boolean; paired: boolean} | null>(null);
+  const [sftpKey, setSftpKey] = useState('');
+  const [targetName, setTargetName] = useState('');
+  const [plan, setPlan] = useState<ArchivePlan | null>(null);
+  const [planError, setPlanError] = useState('');
 
   useEffect(() => {
     api.getConfig().then(setConfig).catch(console.error);
@@ -28,6 +33,17 @@ export function Config() {
     setSaving(false);
   };
 
+  const previewPlan = async () => {
+    if (!config) return;
+    setPlanError('');
+    try {
+      setPlan(await api.planArchive(config));
+    } catch (e: any) {
+      setPlan(null);
+      setPlanError(e.message);
+    }
+  };
+
   const [testing, setTesting] = useState(false);
   const [testMessage, setTestMessage] = useState('');
 
@@ -83,18 +99,59 @@ export function Config() {
 
   if (!config) return <div className="text-gray-500">Loading...</div>;
 
-  const update = (section: string, key: string, value: string | number | boolean) => {
+  const update = (section: string, key: string, value: string | number | boolean | string[] | ArchiveWindow[] | RetentionRule[] | FilterRule[] | WakeOnLAN) => {
     setConfig(prev => prev ? { ...prev, [section]: { ...(prev as any)[section], [key]: value } } : prev);
   };
 
   const archiveMethod = config.archive?.method || 'nfs';
+  const windows = config.archive?.windows ?? [];
+  const updateWindow = (i: number, w: ArchiveWindow) =>
+    update('archive', 'windows', windows.map((old, j) => (j === i ? w : old)));
+  const filters = config.archive?.filters ?? [];
+  const updateFilter = (i: number, f: FilterRule) =>
+    update('archive', 'filters', filters.map((old, j) => (j === i ? f : old)));
+  const splitList = (s: string) => s.split(',').map(v => v.trim()).filter(Boolean);
+  const wake: WakeOnLAN = config.archive?.wake_on_lan ?? { mac: '', broadcast: '', port: 0, timeout_seconds: 0 };
+  const updateWake = (w: Partial<WakeOnLAN>) => update('archive', 'wake_on_lan', { ...wake, ...w });
+  const retention = config.archive?.retention ?? [];
+  const ruleFor = (category: string) =>
+    retention.find(rule => rule.category === category) ?? { category, max_age_days: 0, max_size_gb: 0 };
+  const updateRule = (rule: RetentionRule) =>
+    update('archive', 'retention', [...retention.filter(old => old.category !== rule.category), rule]
+      .filter(rule => rule.max_age_days > 0 || rule.max_size_gb > 0));
+
+  const targets = config.targets ?? [];
+  const setTargets = (t: ArchiveTarget[]) => setConfig(prev => prev ? { ...prev, targets: t } : prev);
+  const addTarget = () => {
+    const name = targetName.trim();
+    if (!name || targets.some(t => t.name === name)) return;
+    setTargets([...targets, {
+      name, method: archiveMethod,
+      nfs: config.nfs, cifs: config.cifs, s3: config.s3, sftp: config.sftp, webdav: config.webdav,
+    }]);
+    setTargetName('');
+  };
+  const moveTarget = (i: number, by: number) => {
+    const t = [...targets];
+    [t[i], t[i + by]] = [t[i + by], t[i]];
+    setTargets(t);
+  };
+
+  const showSFTPKey = async () => {
+    try {
+      const { public_key } = await api.getSFTPKey();
+      setSftpKey(public_key);
+    } catch (e: any) {
+      setSftpKey(`Error: ${e.message}`);
+    }
+  };
 
   return (
     <div className="space-y-6">
       <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
         <h2 className="text-sm font-medium text-gray-300">Archive Server</h2>
         <div className="flex gap-2 mb-3">
-          {['nfs', 'cifs'].map(method => (
+          {['nfs', 'cifs', 's3', 'sftp', 'webdav'].map(method => (
             <button
               key={method}
               onClick={() => update('archive', 'method', method)}
@@ -106,7 +163,7 @@ export function Config() {
             </button>
           ))}
         </div>
-        {archiveMethod === 'nfs' ? (
+        {archiveMethod === 'nfs' && (
           <>
             <div className="grid grid-cols-2 gap-3">
               <div>
@@ -139,7 +196,8 @@ export function Config() {
               {testMessage && <span className={`text-sm ${testMessage.startsWith('Error') ? 'text-red-400' : 'text-green-400'}`}>{testMessage}</span>}
             </div>
           </>
-        ) : (
+        )}
+        {archiveMethod === 'cifs' && (
           <>
             <div className="grid grid-cols-2 gap-3">
               <div>
@@ -192,6 +250,212 @@ export function Config() {
             </div>
           </>
         )}
+        {archiveMethod === 's3' && (
+          <div className="grid grid-cols-2 gap-3">
+            <div className="col-span-2">
+              <label className="text-xs text-gray-500">Endpoint</label>
+              <input
+                value={config.s3?.endpoint ?? ''}
+                onChange={e => update('s3', 'endpoint', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="https://s3.us-west-002.backblazeb2.com"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Bucket</label>
+              <input
+                value={config.s3?.bucket ?? ''}
+                onChange={e => update('s3', 'bucket', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="teslacam"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Prefix</label>
+              <input
+                value={config.s3?.prefix ?? ''}
+                onChange={e => update('s3', 'prefix', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="model3"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Access Key</label>
+              <input
+                value={config.s3?.access_key ?? ''}
+                onChange={e => update('s3', 'access_key', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Secret Key</label>
+              <input
+                type="password"
+                value={config.s3?.secret_key ?? ''}
+                onChange={e => update('s3', 'secret_key', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Region</label>
+              <input
+                value={config.s3?.region ?? ''}
+                onChange={e => update('s3', 'region', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="us-east-1"
+              />
+            </div>
+            <label className="flex items-center gap-2 text-sm text-gray-300 cursor-pointer mt-5">
+              <input
+                type="checkbox"
+                checked={config.s3?.path_style ?? false}
+                onChange={e => update('s3', 'path_style', e.target.checked)}
+                className="rounded border-gray-700 bg-gray-800"
+              />
+              Path-style URLs
+              <span className="text-xs text-gray-500">(MinIO)</span>
+            </label>
+          </div>
+        )}
+        {archiveMethod === 'sftp' && (
+          <>
+            <div className="grid grid-cols-2 gap-3">
+              <div>
+                <label className="text-xs text-gray-500">Server</label>
+                <input
+                  value={config.sftp?.server ?? ''}
+                  onChange={e => update('sftp', 'server', e.target.value)}
+                  className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                  placeholder="192.168.1.100"
+                />
+              </div>
+              <div>
+                <label className="text-xs text-gray-500">Port</label>
+                <input
+                  type="number"
+                  value={config.sftp?.port || 22}
+                  onChange={e => update('sftp', 'port', Number(e.target.value))}
+                  className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                />
+              </div>
+              <div>
+                <label className="text-xs text-gray-500">Username</label>
+                <input
+                  value={config.sftp?.username ?? ''}
+                  onChange={e => update('sftp', 'username', e.target.value)}
+                  className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                  placeholder="pi"
+                />
+              </div>
+              <div>
+                <label className="text-xs text-gray-500">Path</label>
+                <input
+                  value={config.sftp?.path ?? ''}
+                  onChange={e => update('sftp', 'path', e.target.value)}
+                  className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                  placeholder="/srv/teslacam"
+                />
+              </div>
+            </div>
+            <div className="flex items-center gap-2">
+              <button
+                onClick={showSFTPKey}
+                className="px-3 py-1.5 bg-gray-800 hover:bg-gray-700 border border-gray-700 rounded text-sm text-gray-300"
+              >
+                Show Public Key
+              </button>
+              <span className="text-xs text-gray-500">Add it to ~/.ssh/authorized_keys on the server</span>
+            </div>
+            {sftpKey && <code className="block text-xs text-gray-300 break-all bg-gray-800 rounded p-2">{sftpKey}</code>}
+          </>
+        )}
+        {archiveMethod === 'webdav' && (
+          <div className="grid grid-cols-2 gap-3">
+            <div className="col-span-2">
+              <label className="text-xs text-gray-500">URL</label>
+              <input
+                value={config.webdav?.url ?? ''}
+                onChange={e => update('webdav', 'url', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="https://cloud.example.com/remote.php/dav/files/alice/TeslaCam"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Username</label>
+              <input
+                value={config.webdav?.username ?? ''}
+                onChange={e => update('webdav', 'username', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+              />
+            </div>
+            <div>
+              <label className="text-xs text-gray-500">Password</label>
+              <input
+                type="password"
+                value={config.webdav?.password ?? ''}
+                onChange={e => update('webdav', 'password', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+                placeholder="app password"
+              />
+            </div>
+            <div className="col-span-2">
+              <label className="text-xs text-gray-500">Bearer Token (instead of username/password)</label>
+              <input
+                type="password"
+                value={config.webdav?.token ?? ''}
+                onChange={e => update('webdav', 'token', e.target.value)}
+                className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+              />
+            </div>
+          </div>
+        )}
+      </section>
+
+      <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
+        <h2 className="text-sm font-medium text-gray-300">Archive Targets</h2>
+        <p className="text-xs text-gray-500">
+          Archive to the first reachable target, falling back to the next. With no targets the server above is used.
+        </p>
+        {targets.map((t, i) => (
+          <div key={t.name} className="flex items-center gap-2 text-sm">
+            <span className="text-gray-500 w-4">{i + 1}.</span>
+            <span className="flex-1">{t.name} <span className="text-gray-500">({t.method.toUpperCase()})</span></span>
+            <button
+              onClick={() => moveTarget(i, -1)}
+              disabled={i === 0}
+              className="px-2 py-1 bg-gray-800 rounded text-xs disabled:opacity-30"
+            >
+              ↑
+            </button>
+            <button
+              onClick={() => moveTarget(i, 1)}
+              disabled={i === targets.length - 1}
+              className="px-2 py-1 bg-gray-800 rounded text-xs disabled:opacity-30"
+            >
+              ↓
+            </button>
+            <button
+              onClick={() => setTargets(targets.filter((_, j) => j !== i))}
+              className="px-2 py-1 bg-gray-800 hover:bg-red-900 rounded text-xs"
+            >
+              Remove
+            </button>
+          </div>
+        ))}
+        <div className="flex gap-2">
+          <input
+            value={targetName}
+            onChange={e => setTargetName(e.target.value)}
+            className="flex-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            placeholder="Target name, e.g. home"
+          />
+          <button
+            onClick={addTarget}
+            className="px-3 py-1.5 bg-gray-800 hover:bg-gray-700 rounded text-sm"
+          >
+            Add server above as target
+          </button>
+        </div>
       </section>
 
       <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
@@ -206,6 +470,16 @@ export function Config() {
           Archive RecentClips
           <span className="text-xs text-gray-500">(rolling dashcam footage — uses more storage)</span>
         </label>
+        <label className="flex items-center gap-2 text-sm text-gray-300 cursor-pointer">
+          <input
+            type="checkbox"
+            checked={config.archive?.snapshot ?? false}
+            onChange={e => update('archive', 'snapshot', e.target.checked)}
+            className="rounded border-gray-700 bg-gray-800"
+          />
+          Archive from a Snapshot
+          <span className="text-xs text-gray-500">(keeps the drive attached; needs /backingfiles on XFS or btrfs)</span>
+        </label>
         <div>
           <label className="text-xs text-gray-500">Reserve Space (%)</label>
           <div className="flex items-center gap-3 mt-1">
@@ -221,6 +495,310 @@ export function Config() {
           </div>
           <div className="text-xs text-gray-500 mt-0.5">Minimum 2 GB reserved regardless of percentage</div>
         </div>
+        <div>
+          <label className="text-xs text-gray-500">Emergency Threshold</label>
+          <div className="flex items-center gap-3 mt-1">
+            <input
+              type="range"
+              min={0}
+              max={50}
+              value={config.archive?.emergency_free_percent ?? 0}
+              onChange={e => update('archive', 'emergency_free_percent', Number(e.target.value))}
+              className="flex-1"
+            />
+            <span className="text-sm text-gray-300 w-10 text-right">{config.archive?.emergency_free_percent ?? 0}%</span>
+          </div>
+          <div className="text-xs text-gray-500 mt-0.5">
+            RecentClips are freed first unless they are archived. Unarchived clips are only deleted below this much free space (0 = never); starred events never.
+          </div>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Destination Folder</label>
+          <input
+            value={config.archive?.path_template ?? ''}
+            onChange={e => update('archive', 'path_template', e.target.value)}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm font-mono"
+            placeholder="TeslaCam/{category}/{event}"
+          />
+          <div className="text-xs text-gray-500 mt-0.5">
+            Placeholders: {'{category} {year} {month} {day} {event} {vin} {hostname}'} — must include {'{event}'}
+          </div>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Bandwidth Limit (KiB/s)</label>
+          <input
+            type="number"
+            min={0}
+            value={config.archive?.bandwidth_limit_kb ?? 0}
+            onChange={e => update('archive', 'bandwidth_limit_kb', Number(e.target.value))}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+          />
+          <div className="text-xs text-gray-500 mt-0.5">0 = unlimited</div>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Parallel Transfers</label>
+          <input
+            type="number"
+            min={1}
+            max={8}
+            value={config.archive?.workers || 1}
+            onChange={e => update('archive', 'workers', Number(e.target.value))}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+          />
+          <div className="text-xs text-gray-500 mt-0.5">Drops to one near the caution temperature</div>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Archive Windows</label>
+          {windows.map((w, i) => (
+            <div key={i} className="flex flex-wrap items-center gap-1 mt-1">
+              {['mon', 'tue', 'wed', 'thu', 'fri', 'sat', 'sun'].map(day => (
+                <button
+                  key={day}
+                  onClick={() => updateWindow(i, {
+                    ...w,
+                    days: w.days?.includes(day) ? w.days.filter(d => d !== day) : [...(w.days ?? []), day],
+                  })}
+                  className={`px-2 py-1 rounded text-xs ${
+                    w.days?.includes(day) ? 'bg-blue-600' : 'bg-gray-800 text-gray-400'
+                  }`}
+                >
+                  {day}
+                </button>
+              ))}
+              <input
+                type="time"
+                value={w.start}
+                onChange={e => updateWindow(i, { ...w, start: e.target.value })}
+                className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+              />
+              <span className="text-gray-500 text-xs">to</span>
+              <input
+                type="time"
+                value={w.end}
+                onChange={e => updateWindow(i, { ...w, end: e.target.value })}
+                className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+              />
+              <button
+                onClick={() => update('archive', 'windows', windows.filter((_, j) => j !== i))}
+                className="px-2 py-1 text-xs text-red-400"
+              >
+                Remove
+              </button>
+            </div>
+          ))}
+          <button
+            onClick={() => update('archive', 'windows', [...windows, { days: [], start: '22:00', end: '06:00' }])}
+            className="mt-1 px-3 py-1.5 bg-gray-800 rounded text-sm text-gray-300"
+          >
+            Add Window
+          </button>
+          <div className="text-xs text-gray-500 mt-0.5">
+            {windows.length === 0 ? 'Archive any time' : 'No days selected means every day; an end before the start runs past midnight'}
+          </div>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Retention on Archive Server</label>
+          <div className="grid grid-cols-3 gap-2 mt-1 text-xs text-gray-500">
+            <span />
+            <span>Max age (days)</span>
+            <span>Max size (GB)</span>
+          </div>
+          {['SavedClips', 'SentryClips', 'RecentClips'].map(category => {
+            const rule = ruleFor(category);
+            return (
+              <div key={category} className="grid grid-cols-3 gap-2 items-center mt-1">
+                <span className="text-sm text-gray-300">{category.replace('Clips', '')}</span>
+                <input
+                  type="number"
+                  min={0}
+                  value={rule.max_age_days}
+                  onChange={e => updateRule({ ...rule, max_age_days: Number(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                />
+                <input
+                  type="number"
+                  min={0}
+                  step="any"
+                  value={rule.max_size_gb}
+                  onChange={e => updateRule({ ...rule, max_size_gb: Number(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                />
+              </div>
+            );
+          })}
+          <div className="text-xs text-gray-500 mt-0.5">Oldest events are pruned first after each archive run; 0 = no limit</div>
+          <label className="flex items-center gap-2 text-sm text-gray-300 cursor-pointer mt-1">
+            <input
+              type="checkbox"
+              checked={config.archive?.retention_dry_run ?? false}
+              onChange={e => update('archive', 'retention_dry_run', e.target.checked)}
+              className="rounded border-gray-700 bg-gray-800"
+            />
+            Dry run (only report what would be pruned)
+          </label>
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Filters</label>
+          {filters.map((f, i) => (
+            <div key={i} className="mt-1 p-2 bg-gray-800/50 rounded space-y-1">
+              <div className="flex flex-wrap items-center gap-1">
+                <span className="text-xs text-gray-500 w-4">{i + 1}.</span>
+                {['SavedClips', 'SentryClips', 'RecentClips'].map(cat => (
+                  <button
+                    key={cat}
+                    onClick={() => updateFilter(i, {
+                      ...f,
+                      categories: f.categories?.includes(cat) ? f.categories.filter(c => c !== cat) : [...(f.categories ?? []), cat],
+                    })}
+                    className={`px-2 py-1 rounded text-xs ${
+                      f.categories?.includes(cat) ? 'bg-blue-600' : 'bg-gray-800 text-gray-400'
+                    }`}
+                  >
+                    {cat.replace('Clips', '')}
+                  </button>
+                ))}
+                <span className="flex-1" />
+                {(['archive', 'archive_delete', 'skip', 'delete'] as const).map(action => (
+                  <button
+                    key={action}
+                    onClick={() => updateFilter(i, { ...f, action })}
+                    className={`px-2 py-1 rounded text-xs ${
+                      f.action === action ? 'bg-blue-600' : 'bg-gray-800 text-gray-400'
+                    }`}
+                  >
+                    {action.replace('_', ' + ')}
+                  </button>
+                ))}
+                <button
+                  onClick={() => update('archive', 'filters', filters.filter((_, j) => j !== i))}
+                  className="px-2 py-1 text-xs text-red-400"
+                >
+                  Remove
+                </button>
+              </div>
+              <div className="grid grid-cols-2 gap-2">
+                <input
+                  value={(f.cameras ?? []).join(', ')}
+                  onChange={e => updateFilter(i, { ...f, cameras: splitList(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                  placeholder="Cameras, e.g. front, *_repeater"
+                />
+                <input
+                  value={(f.reasons ?? []).join(', ')}
+                  onChange={e => updateFilter(i, { ...f, reasons: splitList(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                  placeholder="Reasons, e.g. sentry_aware_*"
+                />
+                <input
+                  type="number"
+                  min={0}
+                  value={f.min_age_days}
+                  onChange={e => updateFilter(i, { ...f, min_age_days: Number(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                  placeholder="Min age (days)"
+                />
+                <input
+                  type="number"
+                  min={0}
+                  value={f.max_age_days}
+                  onChange={e => updateFilter(i, { ...f, max_age_days: Number(e.target.value) })}
+                  className="bg-gray-800 border border-gray-700 rounded px-2 py-1 text-sm"
+                  placeholder="Max age (days)"
+                />
+              </div>
+            </div>
+          ))}
+          <button
+            onClick={() => update('archive', 'filters', [...filters, {
+              categories: [], cameras: [], reasons: [], min_age_days: 0, max_age_days: 0, action: 'skip',
+            }])}
+            className="mt-1 px-3 py-1.5 bg-gray-800 rounded text-sm text-gray-300"
+          >
+            Add Filter
+          </button>
+          <button
+            onClick={previewPlan}
+            className="mt-1 ml-2 px-3 py-1.5 bg-gray-800 rounded text-sm text-gray-300"
+          >
+            Preview Next Archive
+          </button>
+          <div className="text-xs text-gray-500 mt-0.5">
+            The first matching filter decides each clip; archive keeps it on the cam disk, archive + delete removes it once archived, and clips no filter matches are archived and removed. Empty fields match anything; ages are in days (0 = any).
+          </div>
+          {planError && <div className="text-xs text-red-400 mt-1">{planError}</div>}
+          {plan && (
+            <div className="mt-2 p-2 bg-gray-800/50 rounded text-xs space-y-1">
+              <div className="text-gray-300">
+                {(['archive', 'archive_delete', 'skip', 'delete'] as const).map(action => {
+                  const t = plan.totals[action];
+                  return t ? `${action.replace('_', ' + ')}: ${t.clips} clips (${formatBytes(t.bytes)})` : null;
+                }).filter(Boolean).join(' · ') || 'No clips on the cam disk'}
+                {plan.estimated_seconds > 0 && ` · about ${Math.ceil(plan.estimated_seconds / 60)} min at ${formatBytes(plan.bytes_per_sec)}/s`}
+              </div>
+              {plan.events.map(ev => (
+                <div key={ev.path} className="flex justify-between gap-2 text-gray-400">
+                  <span className="truncate">
+                    {ev.path.replace('TeslaCam/', '')}
+                    {ev.reason && ` · ${ev.reason}`}
+                  </span>
+                  <span className="shrink-0">
+                    {Object.entries(ev.actions).map(([action, n]) => `${n} ${action}`).join(', ')} · {formatBytes(ev.bytes)}
+                  </span>
+                </div>
+              ))}
+              {plan.retention.length > 0 && (
+                <div className="text-gray-400">
+                  Retention {config.archive?.retention_dry_run ? 'would prune' : 'prunes'} {plan.retention.length} archived events
+                  ({formatBytes(plan.retention.reduce((n, ev) => n + ev.bytes, 0))})
+                </div>
+              )}
+              {plan.free_space.length > 0 && (
+                <div className="text-gray-400">
+                  Freeing space deletes {plan.free_space.length} clips from the cam disk
+                  ({formatBytes(plan.free_space.reduce((n, c) => n + c.size, 0))})
+                </div>
+              )}
+            </div>
+          )}
+        </div>
+        {(archiveMethod === 'nfs' || archiveMethod === 'cifs') && (
+          <div>
+            <label className="text-xs text-gray-500">Verify Copies</label>
+            <div className="flex gap-2 mt-1">
+              {[['', 'Off'], ['xxhash', 'xxHash'], ['sha256', 'SHA-256']].map(([algo, label]) => (
+                <button
+                  key={algo}
+                  onClick={() => update('archive', 'verify', algo)}
+                  className={`px-3 py-1.5 rounded text-sm ${
+                    (config.archive?.verify ?? '') === algo ? 'bg-blue-600' : 'bg-gray-800 text-gray-400'
+                  }`}
+                >
+                  {label}
+                </button>
+              ))}
+            </div>
+            <div className="text-xs text-gray-500 mt-0.5">Read back and checksum each clip before deleting it from the cam disk</div>
+          </div>
+        )}
+        {(archiveMethod === 'nfs' || archiveMethod === 'cifs') && (
+          <div>
+            <label className="text-xs text-gray-500">Copy Engine</label>
+            <div className="flex gap-2 mt-1">
+              {[['rsync', 'rsync'], ['native', 'Built-in']].map(([engine, label]) => (
+                <button
+                  key={engine}
+                  onClick={() => update('archive', 'engine', engine)}
+                  className={`px-3 py-1.5 rounded text-sm ${
+                    (config.archive?.engine || 'rsync') === engine ? 'bg-blue-600' : 'bg-gray-800 text-gray-400'
+                  }`}
+                >
+                  {label}
+                </button>
+              ))}
+            </div>
+            <div className="text-xs text-gray-500 mt-0.5">Built-in copies without rsync and resumes interrupted clips</div>
+          </div>
+        )}
       </section>
 
       <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
@@ -275,6 +853,134 @@ export function Config() {
         )}
       </section>
 
+      <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
+        <h2 className="text-sm font-medium text-gray-300">Home Network</h2>
+        <div>
+          <label className="text-xs text-gray-500">WiFi SSIDs</label>
+          <input
+            value={(config.home?.ssids ?? []).join(', ')}
+            onChange={e => update('home', 'ssids', splitList(e.target.value))}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            placeholder="HomeWiFi"
+          />
+        </div>
+        <div>
+          <label className="text-xs text-gray-500">Gateways (IP or MAC)</label>
+          <input
+            value={(config.home?.gateways ?? []).join(', ')}
+            onChange={e => update('home', 'gateways', splitList(e.target.value))}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm font-mono"
+            placeholder="192.168.1.1"
+          />
+          <div className="text-xs text-gray-500 mt-0.5">
+            The Pi is home when the SSID or default gateway matches; with neither set, when the archive server is reachable
+          </div>
+        </div>
+        <div className="grid grid-cols-2 gap-3">
+          <div>
+            <label className="text-xs text-gray-500">Checks to Arrive</label>
+            <input
+              type="number"
+              min={1}
+              value={config.home?.arrive_checks || 2}
+              onChange={e => update('home', 'arrive_checks', Number(e.target.value))}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+          <div>
+            <label className="text-xs text-gray-500">Checks to Leave</label>
+            <input
+              type="number"
+              min={1}
+              value={config.home?.leave_checks || 4}
+              onChange={e => update('home', 'leave_checks', Number(e.target.value))}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+        </div>
+        <div className="text-xs text-gray-500">Checks run every 30 seconds; home/away only changes after this many in a row agree</div>
+        <div>
+          <label className="text-xs text-gray-500">Wake-on-LAN MAC Address</label>
+          <input
+            value={wake.mac}
+            onChange={e => updateWake({ mac: e.target.value })}
+            className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm font-mono"
+            placeholder="00:11:22:33:44:55"
+          />
+          <div className="text-xs text-gray-500 mt-0.5">Wakes a sleeping archive server on arriving home; empty = off</div>
+        </div>
+        <div className="grid grid-cols-3 gap-3">
+          <div>
+            <label className="text-xs text-gray-500">Broadcast</label>
+            <input
+              value={wake.broadcast}
+              onChange={e => updateWake({ broadcast: e.target.value })}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm font-mono"
+              placeholder="255.255.255.255"
+            />
+          </div>
+          <div>
+            <label className="text-xs text-gray-500">Port</label>
+            <input
+              type="number"
+              min={0}
+              value={wake.port || 9}
+              onChange={e => updateWake({ port: Number(e.target.value) })}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+          <div>
+            <label className="text-xs text-gray-500">Timeout (s)</label>
+            <input
+              type="number"
+              min={0}
+              value={wake.timeout_seconds || 180}
+              onChange={e => updateWake({ timeout_seconds: Number(e.target.value) })}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+        </div>
+      </section>
+
+      <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
+        <h2 className="text-sm font-medium text-gray-300">USB Drives</h2>
+        <div className="grid grid-cols-3 gap-3">
+          <div>
+            <label className="text-xs text-gray-500">Music (GB)</label>
+            <input
+              type="number"
+              min={0}
+              value={config.drives?.music_gb ?? 0}
+              onChange={e => update('drives', 'music_gb', Number(e.target.value))}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+          <div>
+            <label className="text-xs text-gray-500">LightShow (GB)</label>
+            <input
+              type="number"
+              min={0}
+              value={config.drives?.lightshow_gb ?? 0}
+              onChange={e => update('drives', 'lightshow_gb', Number(e.target.value))}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+          <div>
+            <label className="text-xs text-gray-500">Boombox (GB)</label>
+            <input
+              type="number"
+              min={0}
+              value={config.drives?.boombox_gb ?? 0}
+              onChange={e => update('drives', 'boombox_gb', Number(e.target.value))}
+              className="w-full mt-1 bg-gray-800 border border-gray-700 rounded px-3 py-2 text-sm"
+            />
+          </div>
+        </div>
+        <div className="text-xs text-gray-500">
+          Extra drives next to the cam disk; 0 = none. Each is created at the next start. A cam disk created before they were set took the free space and must be recreated to make room
+        </div>
+      </section>
+
       <section className="bg-gray-900 rounded-lg p-4 border border-gray-800 space-y-3">
         <h2 className="text-sm font-medium text-gray-300">Notifications</h2>
         <div>
@@ -1,6 +1,6 @@
 import { useEffect, useState } from 'react';
 import { api } from '../lib/api';
-import type { Status, UpdateInfo } from '../lib/api';
+import type { ArchiveProgress, Status, UpdateInfo } from '../lib/api';
 import { formatBytes } from '../lib/format';
 import { WSClient } from '../lib/ws';
 
@@ -21,12 +21,14 @@ function signalPercent(dbm: number): number {
 
 export function Dashboard() {
   const [status, setStatus] = useState<Status | null>(null);
+  const [progress, setProgress] = useState<ArchiveProgress | null>(null);
   const [updateInfo, setUpdateInfo] = useState<UpdateInfo | null>(null);
   const [updating, setUpdating] = useState(false);
   const [checkingUpdate, setCheckingUpdate] = useState(false);
 
   useEffect(() => {
     api.getStatus().then(setStatus).catch(console.error);
+    api.getArchiveProgress().then(setProgress).catch(console.error);
     const interval = setInterval(() => {
       api.getStatus().then(setStatus).catch(console.error);
     }, 10000);
@@ -36,6 +38,8 @@ export function Dashboard() {
     ws.onMessage((data) => {
       if (data.type === 'state') {
         setStatus(prev => prev ? { ...prev, state: data.state } : prev);
+      } else if (data.type === 'archive_progress') {
+        setProgress(data);
       }
     });
 
@@ -66,6 +70,8 @@ export function Dashboard() {
 
   if (!status) return <div className="text-gray-500">Loading...</div>;
 
+  const retention = status.archive_result?.retention;
+  const archivePercent = progress?.bytes_total ? Math.round((progress.bytes_done / progress.bytes_total) * 100) : 0;
   const diskPercent = status.disk_total ? Math.round(((status.disk_used || 0) / status.disk_total) * 100) : 0;
 
   return (
@@ -110,6 +116,23 @@ export function Dashboard() {
           {status.archive_clips > 0 && (
             <div className="text-xs text-gray-500 mt-1">
               {status.archive_clips} clips ({formatBytes(status.archive_bytes)})
+              {status.archive_target && ` to ${status.archive_target}`}
+              {status.archive_result?.failed ? <span className="text-red-400">, {status.archive_result.failed} failed</span> : null}
+            </div>
+          )}
+          {status.archive_result?.categories && (
+            <div className="text-xs text-gray-500 mt-0.5">
+              {Object.entries(status.archive_result.categories)
+                .filter(([, t]) => t.clips > 0)
+                .map(([name, t]) => `${name.replace('Clips', '')}: ${t.events || t.clips} ${t.events ? 'events' : 'clips'}`)
+                .join(' · ')}
+            </div>
+          )}
+          {!status.archive_window_open && (
+            <div className="text-xs text-yellow-400 mt-0.5">
+              {status.next_archive_window
+                ? `Next archive window: ${new Date(status.next_archive_window).toLocaleString()}`
+                : 'No archive window configured'}
             </div>
           )}
           {status.archive_count > 0 && (
@@ -126,6 +149,12 @@ export function Dashboard() {
               <div>
                 <div className="text-lg font-medium">{status.wifi_ssid}</div>
                 <div className="text-xs text-gray-500">{status.wifi_ip}</div>
+                {status.presence && (
+                  <div className="text-xs text-gray-500">
+                    {status.presence.home ? 'Home' : 'Away'}: {status.presence.reason}
+                    {status.presence.pending > 0 && ` (changing, ${status.presence.pending}/${status.presence.needed} checks)`}
+                  </div>
+                )}
               </div>
               <div className="text-right">
                 <div className="text-sm text-gray-300">{status.wifi_signal_dbm} dBm</div>
@@ -140,6 +169,63 @@ export function Dashboard() {
           </div>
         )}
       </div>
+      {progress?.running && (
+        <div className="bg-gray-900 rounded-lg p-4 border border-gray-800">
+          <div className="flex items-center justify-between mb-1">
+            <div className="text-sm text-gray-400">{progress.paused ? 'Archiving paused' : 'Archiving'}</div>
+            <div className="flex items-center gap-2 text-xs text-gray-500">
+              {!progress.paused && formatBytes(progress.bytes_per_sec) + '/s'}
+              {!progress.paused && progress.eta_seconds > 0 && ` · ${Math.ceil(progress.eta_seconds / 60)} min left`}
+              <button
+                onClick={() => (progress.paused ? api.resumeArchive() : api.pauseArchive())}
+                className="px-2 py-0.5 bg-gray-800 hover:bg-gray-700 rounded transition-colors"
+              >
+                {progress.paused ? 'Resume' : 'Pause'}
+              </button>
+              <button
+                onClick={() => api.cancelArchive()}
+                className="px-2 py-0.5 bg-gray-800 hover:bg-gray-700 rounded text-red-400 transition-colors"
+              >
+                Cancel
+              </button>
+            </div>
+          </div>
+          <div className="w-full bg-gray-800 rounded-full h-2 mt-2">
+            <div className="bg-orange-500 h-2 rounded-full" style={{ width: `${archivePercent}%` }} />
+          </div>
+          <div className="flex items-center justify-between text-xs text-gray-500 mt-1">
+            <span>
+              {progress.files_done} / {progress.files_total} files
+              {progress.files_failed > 0 && <span className="text-red-400"> ({progress.files_failed} failed)</span>}
+            </span>
+            <span>{formatBytes(progress.bytes_done)} / {formatBytes(progress.bytes_total)}</span>
+          </div>
+          {(progress.active_files ?? (progress.current_file ? [progress.current_file] : [])).map(f => (
+            <div key={f} className="text-xs text-gray-600 mt-1 truncate">{f}</div>
+          ))}
+        </div>
+      )}
+      {retention && (retention.events.length > 0 || retention.error) && (
+        <div className="bg-gray-900 rounded-lg p-4 border border-gray-800">
+          <div className="flex items-center justify-between mb-1">
+            <div className="text-sm text-gray-400">
+              {retention.dry_run ? 'Retention dry run: would prune' : 'Retention pruned'}
+            </div>
+            <div className="text-xs text-gray-500">
+              {retention.events.length} events · {formatBytes(retention.bytes)}
+            </div>
+          </div>
+          {retention.error && <div className="text-xs text-red-400 mb-1">{retention.error}</div>}
+          <div className="max-h-40 overflow-y-auto space-y-0.5">
+            {retention.events.map(ev => (
+              <div key={ev.path} className="text-xs text-gray-500 flex justify-between gap-2">
+                <span className="truncate">{ev.path}</span>
+                <span className="shrink-0">{ev.reason} · {formatBytes(ev.bytes)}</span>
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
       {status.last_error && (
         <div className="bg-red-900/30 border border-red-800 rounded-lg p-3 text-sm text-red-300">
           {status.last_error}
@@ -7,11 +7,13 @@ export function Files() {
   const [path, setPath] = useState('TeslaCam');
   const [files, setFiles] = useState<FileEntry[]>([]);
   const [error, setError] = useState('');
+  const [readOnly, setReadOnly] = useState(false);
 
   const loadFiles = (p: string) => {
     setPath(p);
     setError('');
     api.getFiles(p).then(setFiles).catch(e => setError(e.message));
+    api.getStatus().then(s => setReadOnly(!s.cam_disk_mounted)).catch(console.error);
   };
 
   useEffect(() => { loadFiles('TeslaCam'); }, []);
@@ -34,6 +36,11 @@ export function Files() {
       </div>
 
       {error && <div className="text-red-400 text-sm">{error}</div>}
+      {readOnly && (
+        <div className="text-xs text-gray-500">
+          The car is using the drive — showing a read-only snapshot, refreshed every couple of minutes
+        </div>
+      )}
 
       <div className="bg-gray-900 rounded-lg border border-gray-800 divide-y divide-gray-800">
         {files.length === 0 && <div className="p-4 text-gray-500 text-sm">No files</div>}
@@ -49,23 +56,37 @@ export function Files() {
               )}
             </div>
             <div className="flex items-center gap-3 text-sm text-gray-500">
+              {file.is_dir && /^TeslaCam\/(Saved|Sentry|Recent)Clips\/[^/]+$/.test(file.path) && (
+                <button
+                  onClick={async () => {
+                    await api.protectEvent(file.path, !file.protected);
+                    loadFiles(path);
+                  }}
+                  className={file.protected ? 'text-yellow-400' : 'text-gray-600 hover:text-yellow-400'}
+                  title={file.protected ? 'Protected from free-space cleanup' : 'Protect from free-space cleanup'}
+                >
+                  {file.protected ? '★' : '☆'}
+                </button>
+              )}
               {!file.is_dir && <span>{formatBytes(file.size)}</span>}
               {!file.is_dir && (
                 <a href={api.downloadURL(file.path)} className="text-blue-400 hover:text-blue-300">
                   Download
                 </a>
               )}
-              <button
-                onClick={async () => {
-                  if (confirm(`Delete ${file.name}?`)) {
-                    await api.deleteFile(file.path);
-                    loadFiles(path);
-                  }
-                }}
-                className="text-red-400 hover:text-red-300"
-              >
-                Delete
-              </button>
+              {!readOnly && (
+                <button
+                  onClick={async () => {
+                    if (confirm(`Delete ${file.name}?`)) {
+                      await api.deleteFile(file.path);
+                      loadFiles(path);
+                    }
+                  }}
+                  className="text-red-400 hover:text-red-300"
+                >
+                  Delete
+                </button>
+              )}
             </div>
           </div>
         ))}
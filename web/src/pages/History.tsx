@@ -0,0 +1,89 @@
+import { useEffect, useState } from 'react';
+import { api } from '../lib/api';
+import type { ArchiveRun } from '../lib/api';
+import { formatBytes } from '../lib/format';
+
+const statusColors: Record<string, string> = {
+  success: 'text-green-400',
+  partial: 'text-yellow-400',
+  failed: 'text-red-400',
+  cancelled: 'text-gray-400',
+};
+
+export function History() {
+  const [runs, setRuns] = useState<ArchiveRun[]>([]);
+  const [selected, setSelected] = useState<ArchiveRun | null>(null);
+
+  useEffect(() => {
+    api.getArchiveHistory().then(setRuns).catch(console.error);
+  }, []);
+
+  const open = (id: number) => {
+    if (selected?.id === id) {
+      setSelected(null);
+      return;
+    }
+    api.getArchiveRun(id).then(setSelected).catch(console.error);
+  };
+
+  if (runs.length === 0) {
+    return <div className="text-gray-500 text-sm">No archive runs yet</div>;
+  }
+
+  return (
+    <div className="space-y-2">
+      {runs.map(run => (
+        <div key={run.id} className="bg-gray-900 rounded-lg border border-gray-800">
+          <button onClick={() => open(run.id)} className="w-full p-3 text-left">
+            <div className="flex items-center justify-between">
+              <div className="text-sm">
+                <span className={statusColors[run.status]}>{run.status}</span>
+                <span className="text-gray-400 ml-2">{new Date(run.start).toLocaleString()}</span>
+              </div>
+              <div className="text-xs text-gray-500">
+                {run.clips} clips · {formatBytes(run.bytes)}
+                {run.bytes_per_sec > 0 && ` · ${formatBytes(run.bytes_per_sec)}/s`}
+              </div>
+            </div>
+            {run.error && <div className="text-xs text-red-400 mt-1 truncate">{run.error}</div>}
+          </button>
+          {selected?.id === run.id && (
+            <div className="border-t border-gray-800 p-3 text-xs text-gray-400 space-y-1">
+              <div>
+                {selected.target && `${selected.target}: `}{selected.backend} {selected.destination}{selected.snapshot && ' (from snapshot)'} · {Math.round((new Date(selected.end).getTime() - new Date(selected.start).getTime()) / 1000)}s
+              </div>
+              {Object.entries(selected.categories ?? {}).map(([name, t]) => (
+                <div key={name}>
+                  {name}: {t.events} events, {t.clips} clips ({formatBytes(t.bytes)})
+                  {t.failed > 0 && <span className="text-red-400">, {t.failed} failed</span>}
+                  {t.skipped > 0 && `, ${t.skipped} skipped`}
+                </div>
+              ))}
+              {selected.archived_events?.map(ev => (
+                <div key={ev.dir} className="text-gray-500">
+                  {new Date(ev.timestamp).toLocaleString()} · {ev.reason ?? 'unknown'}
+                  {ev.city && ` · ${ev.city}`}
+                </div>
+              ))}
+              {selected.failed_files?.map(f => (
+                <div key={f.path} className="text-red-400 truncate">
+                  {f.path}: {f.error}
+                </div>
+              ))}
+              {selected.filtered?.map(o => (
+                <div key={`${o.rule}-${o.action}`}>
+                  Filter {o.rule}: {o.action} {o.clips} clips ({formatBytes(o.bytes)})
+                </div>
+              ))}
+              {selected.retention?.events.map(ev => (
+                <div key={ev.path} className="text-gray-500">
+                  {selected.retention?.dry_run ? 'Would prune' : 'Pruned'} {ev.path} ({ev.reason}, {formatBytes(ev.bytes)})
+                </div>
+              ))}
+            </div>
+          )}
+        </div>
+      ))}
+    </div>
+  );
+}